@@ -0,0 +1,425 @@
+package cherrypymock
+
+import (
+	"context"
+
+	"github.com/finarfin/go-salt-netapi-client/cherrypy"
+)
+
+var _ cherrypy.API = (*Mock)(nil)
+
+// Login records the call and returns the error set with Return()
+func (m *Mock) Login(ctx context.Context) error {
+	ret, err := m.called("Login")
+	if err != nil {
+		return err
+	}
+
+	return ret.error()
+}
+
+// Logout records the call and returns the error set with Return()
+func (m *Mock) Logout(ctx context.Context) error {
+	ret, err := m.called("Logout")
+	if err != nil {
+		return err
+	}
+
+	return ret.error()
+}
+
+// Minion records the call with id and returns the *cherrypy.Minion and error set with Return()
+func (m *Mock) Minion(ctx context.Context, id string) (*cherrypy.Minion, error) {
+	ret, err := m.called("Minion", id)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).(*cherrypy.Minion)
+	return res, ret.error()
+}
+
+// Minions records the call and returns the []cherrypy.Minion and error set with Return()
+func (m *Mock) Minions(ctx context.Context) ([]cherrypy.Minion, error) {
+	ret, err := m.called("Minions")
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).([]cherrypy.Minion)
+	return res, ret.error()
+}
+
+// PreviewTarget records the call with t and returns the []string and error set with Return()
+func (m *Mock) PreviewTarget(ctx context.Context, t cherrypy.Target) ([]string, error) {
+	ret, err := m.called("PreviewTarget", t)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).([]string)
+	return res, ret.error()
+}
+
+// SubmitJob records the call with job and returns the *cherrypy.AsyncMinionJobResult and error set with Return()
+func (m *Mock) SubmitJob(ctx context.Context, job cherrypy.MinionJob) (*cherrypy.AsyncMinionJobResult, error) {
+	ret, err := m.called("SubmitJob", job)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).(*cherrypy.AsyncMinionJobResult)
+	return res, ret.error()
+}
+
+// SubmitJobs records the call with jobs and returns the []cherrypy.AsyncMinionJobResult and error set with Return()
+func (m *Mock) SubmitJobs(ctx context.Context, jobs []cherrypy.MinionJob) ([]cherrypy.AsyncMinionJobResult, error) {
+	ret, err := m.called("SubmitJobs", jobs)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).([]cherrypy.AsyncMinionJobResult)
+	return res, ret.error()
+}
+
+// Job records the call with id and returns the *cherrypy.JobDetails and error set with Return()
+func (m *Mock) Job(ctx context.Context, id string) (*cherrypy.JobDetails, error) {
+	ret, err := m.called("Job", id)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).(*cherrypy.JobDetails)
+	return res, ret.error()
+}
+
+// Jobs records the call and returns the []cherrypy.Job and error set with Return()
+func (m *Mock) Jobs(ctx context.Context) ([]cherrypy.Job, error) {
+	ret, err := m.called("Jobs")
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).([]cherrypy.Job)
+	return res, ret.error()
+}
+
+// QueryJobs records the call with q and returns the []cherrypy.Job and error set with Return()
+func (m *Mock) QueryJobs(ctx context.Context, q cherrypy.JobQuery) ([]cherrypy.Job, error) {
+	ret, err := m.called("QueryJobs", q)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).([]cherrypy.Job)
+	return res, ret.error()
+}
+
+// WaitForJob records the call with id and opts and returns the *cherrypy.JobWaitResult and error set with Return()
+func (m *Mock) WaitForJob(ctx context.Context, id string, opts cherrypy.WaitOptions) (*cherrypy.JobWaitResult, error) {
+	ret, err := m.called("WaitForJob", id, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).(*cherrypy.JobWaitResult)
+	return res, ret.error()
+}
+
+// Keys records the call and returns the *cherrypy.KeyResult and error set with Return()
+func (m *Mock) Keys(ctx context.Context) (*cherrypy.KeyResult, error) {
+	ret, err := m.called("Keys")
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).(*cherrypy.KeyResult)
+	return res, ret.error()
+}
+
+// Key records the call with id and returns the string and error set with Return()
+func (m *Mock) Key(ctx context.Context, id string) (string, error) {
+	ret, err := m.called("Key", id)
+	if err != nil {
+		return "", err
+	}
+
+	res, _ := ret.get(0).(string)
+	return res, ret.error()
+}
+
+// GenerateKeyPair records the call with id, keySize and force and returns the *cherrypy.MinionKeyPair and error set with Return()
+func (m *Mock) GenerateKeyPair(ctx context.Context, id string, keySize int, force bool) (*cherrypy.MinionKeyPair, error) {
+	ret, err := m.called("GenerateKeyPair", id, keySize, force)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).(*cherrypy.MinionKeyPair)
+	return res, ret.error()
+}
+
+// AcceptKeys records the call with match and opts and returns the []cherrypy.KeyChange and error set with Return()
+func (m *Mock) AcceptKeys(ctx context.Context, match string, opts cherrypy.KeyActionOptions) ([]cherrypy.KeyChange, error) {
+	ret, err := m.called("AcceptKeys", match, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).([]cherrypy.KeyChange)
+	return res, ret.error()
+}
+
+// AcceptKeyDict records the call with keys and opts and returns the []cherrypy.KeyChange and error set with Return()
+func (m *Mock) AcceptKeyDict(ctx context.Context, keys cherrypy.KeyResult, opts cherrypy.KeyActionOptions) ([]cherrypy.KeyChange, error) {
+	ret, err := m.called("AcceptKeyDict", keys, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).([]cherrypy.KeyChange)
+	return res, ret.error()
+}
+
+// RejectKeys records the call with match and opts and returns the []cherrypy.KeyChange and error set with Return()
+func (m *Mock) RejectKeys(ctx context.Context, match string, opts cherrypy.KeyActionOptions) ([]cherrypy.KeyChange, error) {
+	ret, err := m.called("RejectKeys", match, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).([]cherrypy.KeyChange)
+	return res, ret.error()
+}
+
+// DeleteKeys records the call with match and returns the []cherrypy.KeyChange and error set with Return()
+func (m *Mock) DeleteKeys(ctx context.Context, match string) ([]cherrypy.KeyChange, error) {
+	ret, err := m.called("DeleteKeys", match)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).([]cherrypy.KeyChange)
+	return res, ret.error()
+}
+
+// KeyFingerprints records the call with match and hashType and returns the *cherrypy.KeyFingerprints and error set with Return()
+func (m *Mock) KeyFingerprints(ctx context.Context, match string, hashType string) (*cherrypy.KeyFingerprints, error) {
+	ret, err := m.called("KeyFingerprints", match, hashType)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).(*cherrypy.KeyFingerprints)
+	return res, ret.error()
+}
+
+// RunCommand records the call with cmd and returns the value and error set with Return()
+func (m *Mock) RunCommand(ctx context.Context, cmd cherrypy.Command) (interface{}, error) {
+	ret, err := m.called("RunCommand", cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret.get(0), ret.error()
+}
+
+// RunCommands records the call with cmds and returns the values and error set with Return()
+func (m *Mock) RunCommands(ctx context.Context, cmds []cherrypy.Command) ([]interface{}, error) {
+	ret, err := m.called("RunCommands", cmds)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).([]interface{})
+	return res, ret.error()
+}
+
+// RunLocalCommand records the call with cmd and returns the cherrypy.LocalResult and error set with Return()
+func (m *Mock) RunLocalCommand(ctx context.Context, cmd cherrypy.Command) (cherrypy.LocalResult, error) {
+	ret, err := m.called("RunLocalCommand", cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).(cherrypy.LocalResult)
+	return res, ret.error()
+}
+
+// RunRunnerCommand records the call with cmd and returns the *cherrypy.MasterResult and error set with Return()
+func (m *Mock) RunRunnerCommand(ctx context.Context, cmd cherrypy.Command) (*cherrypy.MasterResult, error) {
+	ret, err := m.called("RunRunnerCommand", cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).(*cherrypy.MasterResult)
+	return res, ret.error()
+}
+
+// RunWheelCommand records the call with cmd and returns the *cherrypy.MasterResult and error set with Return()
+func (m *Mock) RunWheelCommand(ctx context.Context, cmd cherrypy.Command) (*cherrypy.MasterResult, error) {
+	ret, err := m.called("RunWheelCommand", cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).(*cherrypy.MasterResult)
+	return res, ret.error()
+}
+
+// RunLocalAsyncCommand records the call with cmd and returns the *cherrypy.AsyncMinionJobResult and error set with Return()
+func (m *Mock) RunLocalAsyncCommand(ctx context.Context, cmd cherrypy.Command) (*cherrypy.AsyncMinionJobResult, error) {
+	ret, err := m.called("RunLocalAsyncCommand", cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).(*cherrypy.AsyncMinionJobResult)
+	return res, ret.error()
+}
+
+// RunRunnerAsyncCommand records the call with cmd and returns the *cherrypy.AsyncMasterJobResult and error set with Return()
+func (m *Mock) RunRunnerAsyncCommand(ctx context.Context, cmd cherrypy.Command) (*cherrypy.AsyncMasterJobResult, error) {
+	ret, err := m.called("RunRunnerAsyncCommand", cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).(*cherrypy.AsyncMasterJobResult)
+	return res, ret.error()
+}
+
+// RunWheelAsyncCommand records the call with cmd and returns the *cherrypy.AsyncMasterJobResult and error set with Return()
+func (m *Mock) RunWheelAsyncCommand(ctx context.Context, cmd cherrypy.Command) (*cherrypy.AsyncMasterJobResult, error) {
+	ret, err := m.called("RunWheelAsyncCommand", cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).(*cherrypy.AsyncMasterJobResult)
+	return res, ret.error()
+}
+
+// RunSSHCommand records the call with cmd and returns the cherrypy.SSHResult and error set with Return()
+func (m *Mock) RunSSHCommand(ctx context.Context, cmd cherrypy.Command) (cherrypy.SSHResult, error) {
+	ret, err := m.called("RunSSHCommand", cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).(cherrypy.SSHResult)
+	return res, ret.error()
+}
+
+// Hook records the call with id and data and returns the error set with Return()
+func (m *Mock) Hook(ctx context.Context, id string, data interface{}) error {
+	ret, err := m.called("Hook", id, data)
+	if err != nil {
+		return err
+	}
+
+	return ret.error()
+}
+
+// Stats records the call and returns the map[string]interface{} and error set with Return()
+func (m *Mock) Stats(ctx context.Context) (map[string]interface{}, error) {
+	ret, err := m.called("Stats")
+	if err != nil {
+		return nil, err
+	}
+
+	res, _ := ret.get(0).(map[string]interface{})
+	return res, ret.error()
+}
+
+// Session records the call and returns the *cherrypy.Session set with Return()
+func (m *Mock) Session() *cherrypy.Session {
+	ret, err := m.called("Session")
+	if err != nil {
+		return nil
+	}
+
+	res, _ := ret.get(0).(*cherrypy.Session)
+	return res
+}
+
+// Token records the call and returns the string set with Return()
+func (m *Mock) Token() string {
+	ret, err := m.called("Token")
+	if err != nil {
+		return ""
+	}
+
+	res, _ := ret.get(0).(string)
+	return res
+}
+
+// SetToken records the call with token; calls are recorded even without an expectation
+func (m *Mock) SetToken(token string) {
+	m.called("SetToken", token)
+}
+
+/*
+RunBatch calls fn with each cherrypy.LocalResult of a []cherrypy.LocalResult set as the first return value
+
+Calling fn stops at the first error which is returned.
+*/
+func (m *Mock) RunBatch(ctx context.Context, cmd cherrypy.Command, opts cherrypy.BatchOptions, fn func(cherrypy.LocalResult) error) error {
+	ret, err := m.called("RunBatch", cmd, opts)
+	if err != nil {
+		return err
+	}
+
+	batches, _ := ret.get(0).([]cherrypy.LocalResult)
+	for _, batch := range batches {
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+
+	return ret.error()
+}
+
+// Events records the call and returns the channel (bidirectional or receive-only) and error set with Return()
+func (m *Mock) Events(ctx context.Context) (<-chan cherrypy.Event, error) {
+	ret, err := m.called("Events")
+	if err != nil {
+		return nil, err
+	}
+
+	return events(ret.get(0)), ret.error()
+}
+
+// EventsWithSaltToken records the call with saltToken and returns the channel (bidirectional or receive-only) and error set with Return()
+func (m *Mock) EventsWithSaltToken(ctx context.Context, saltToken string) (<-chan cherrypy.Event, error) {
+	ret, err := m.called("EventsWithSaltToken", saltToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return events(ret.get(0)), ret.error()
+}
+
+// WebSocketEvents records the call with opts and returns the channel (bidirectional or receive-only) and error set with Return()
+func (m *Mock) WebSocketEvents(ctx context.Context, opts cherrypy.WebSocketOptions) (<-chan cherrypy.Event, error) {
+	ret, err := m.called("WebSocketEvents", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return events(ret.get(0)), ret.error()
+}
+
+// events accepts both bidirectional and receive-only channels as return values
+func events(v interface{}) <-chan cherrypy.Event {
+	switch ch := v.(type) {
+	case chan cherrypy.Event:
+		return ch
+	case <-chan cherrypy.Event:
+		return ch
+	}
+
+	return nil
+}
@@ -0,0 +1,255 @@
+/*
+Package cherrypymock provides a mock implementing cherrypy.API for unit tests
+
+Calls are recorded and answered by expectations registered with On(). Arguments are compared
+with reflect.DeepEqual unless Anything is used; contexts are not recorded.
+Calls without a matching expectation return zero values and ErrorUnexpectedCall.
+
+Example usage:
+	salt := cherrypymock.New()
+	salt.On("RunLocalCommand", cherrypymock.Anything).Return(cherrypy.LocalResult{
+		"web1": {Return: true, Success: true},
+	}, nil).Once()
+
+	deployer := Deployer{Salt: salt}
+	...
+
+	salt.AssertExpectations(t)
+*/
+package cherrypymock
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Anything matches any value of an argument
+const Anything = "cherrypymock.Anything"
+
+// ErrorUnexpectedCall indicates a method was called without a matching expectation
+var ErrorUnexpectedCall = errors.New("unexpected call")
+
+// TestingT is the subset of testing.T used by assertions
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// Call is a recorded call of a method
+type Call struct {
+	Method    string
+	Arguments []interface{}
+}
+
+func (c Call) String() string {
+	args := make([]string, len(c.Arguments))
+	for i, arg := range c.Arguments {
+		args[i] = fmt.Sprintf("%#v", arg)
+	}
+
+	return fmt.Sprintf("%s(%s)", c.Method, strings.Join(args, ", "))
+}
+
+// Expectation describes arguments of an expected call and values to return
+type Expectation struct {
+	method    string
+	arguments []interface{}
+	returns   []interface{}
+	times     int
+	calls     int
+}
+
+/*
+Return sets values returned by the method in the order of its results
+
+Nil can be used for zero values. For RunBatch, a []cherrypy.LocalResult
+may be returned before the error which will be passed to the callback.
+*/
+func (e *Expectation) Return(values ...interface{}) *Expectation {
+	e.returns = values
+	return e
+}
+
+// Times limits the number of calls the expectation will match
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// Once limits the expectation to match a single call
+func (e *Expectation) Once() *Expectation {
+	return e.Times(1)
+}
+
+func (e *Expectation) matches(call Call) bool {
+	if e.method != call.Method || (e.times > 0 && e.calls >= e.times) {
+		return false
+	}
+
+	// Expectations without arguments match any arguments
+	if len(e.arguments) == 0 {
+		return true
+	}
+
+	if len(e.arguments) != len(call.Arguments) {
+		return false
+	}
+
+	for i, arg := range e.arguments {
+		if arg != Anything && !reflect.DeepEqual(arg, call.Arguments[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// satisfied returns whether an expectation was called as many times as expected or at least once
+func (e *Expectation) satisfied() bool {
+	if e.times > 0 {
+		return e.calls == e.times
+	}
+
+	return e.calls > 0
+}
+
+func (e *Expectation) String() string {
+	return Call{Method: e.method, Arguments: e.arguments}.String()
+}
+
+// Mock implements cherrypy.API; it is safe for concurrent use
+type Mock struct {
+	mu           sync.Mutex
+	calls        []Call
+	expectations []*Expectation
+}
+
+// New creates a mock without expectations
+func New() *Mock {
+	return &Mock{}
+}
+
+/*
+On registers an expectation for calls of the method with the arguments
+
+Arguments exclude the context (and the callback of RunBatch); when none are given, any arguments match.
+Expectations are matched in the order they were registered.
+*/
+func (m *Mock) On(method string, arguments ...interface{}) *Expectation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := &Expectation{method: method, arguments: arguments}
+	m.expectations = append(m.expectations, e)
+
+	return e
+}
+
+// Calls returns all recorded calls in order
+func (m *Mock) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Call{}, m.calls...)
+}
+
+// CallsTo returns recorded calls of the method in order
+func (m *Mock) CallsTo(method string) []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	calls := []Call{}
+	for _, c := range m.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+
+	return calls
+}
+
+// AssertExpectations reports expectations which were not called as many times as expected
+func (m *Mock) AssertExpectations(t TestingT) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ok := true
+	for _, e := range m.expectations {
+		if e.satisfied() {
+			continue
+		}
+
+		ok = false
+		if e.times > 0 {
+			t.Errorf("cherrypymock: expected %s to be called %d time(s), called %d time(s)", e, e.times, e.calls)
+		} else {
+			t.Errorf("cherrypymock: expected %s to be called", e)
+		}
+	}
+
+	return ok
+}
+
+// AssertCalled reports if the method was not called with the arguments
+func (m *Mock) AssertCalled(t TestingT, method string, arguments ...interface{}) bool {
+	expected := &Expectation{method: method, arguments: arguments}
+	for _, c := range m.Calls() {
+		if expected.matches(c) {
+			return true
+		}
+	}
+
+	t.Errorf("cherrypymock: expected call %s, calls: %v", expected, m.Calls())
+	return false
+}
+
+// AssertNotCalled reports if the method was called
+func (m *Mock) AssertNotCalled(t TestingT, method string) bool {
+	if calls := m.CallsTo(method); len(calls) > 0 {
+		t.Errorf("cherrypymock: unexpected call %s", calls[0])
+		return false
+	}
+
+	return true
+}
+
+// called records the call and returns values of the first matching expectation
+func (m *Mock) called(method string, arguments ...interface{}) (returns, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	call := Call{Method: method, Arguments: arguments}
+	m.calls = append(m.calls, call)
+
+	for _, e := range m.expectations {
+		if e.matches(call) {
+			e.calls++
+			return returns(e.returns), nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrorUnexpectedCall, call)
+}
+
+type returns []interface{}
+
+// get returns the value at the index; nil if it was not set
+func (r returns) get(i int) interface{} {
+	if i < len(r) {
+		return r[i]
+	}
+
+	return nil
+}
+
+// error returns the last value as error
+func (r returns) error() error {
+	if len(r) == 0 {
+		return nil
+	}
+
+	err, _ := r[len(r)-1].(error)
+	return err
+}
@@ -0,0 +1,150 @@
+package cherrypymock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/finarfin/go-salt-netapi-client/cherrypy"
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestExpectations(t *testing.T) {
+	m := New()
+	cmd := cherrypy.Command{
+		Target:   cherrypy.ExpressionTarget{Expression: "web*", Type: cherrypy.Glob},
+		Function: "test.ping",
+	}
+
+	m.On("RunLocalCommand", cmd).Return(cherrypy.LocalResult{"web1": {Return: true, Success: true}}, nil).Once()
+	m.On("RunLocalCommand", Anything).Return(nil, errors.New("failed"))
+	m.On("Key", "web1").Return("aa:bb", nil)
+	m.On("Logout").Return(cherrypy.ErrorNotAuthenticated)
+
+	res, err := m.RunLocalCommand(context.Background(), cmd)
+	assert.NoError(t, err)
+	assert.Equal(t, cherrypy.LocalResult{"web1": {Return: true, Success: true}}, res)
+
+	res, err = m.RunLocalCommand(context.Background(), cmd)
+	assert.EqualError(t, err, "failed")
+	assert.Nil(t, res)
+
+	key, err := m.Key(context.Background(), "web1")
+	assert.NoError(t, err)
+	assert.Equal(t, "aa:bb", key)
+
+	assert.Equal(t, cherrypy.ErrorNotAuthenticated, m.Logout(context.Background()))
+
+	_, err = m.Key(context.Background(), "web2")
+	assert.True(t, errors.Is(err, ErrorUnexpectedCall))
+	assert.Contains(t, err.Error(), `Key("web2")`)
+
+	assert.Len(t, m.Calls(), 5)
+	assert.Equal(t, []Call{
+		{Method: "Key", Arguments: []interface{}{"web1"}},
+		{Method: "Key", Arguments: []interface{}{"web2"}},
+	}, m.CallsTo("Key"))
+
+	r := &recorder{}
+	assert.True(t, m.AssertExpectations(r))
+	assert.True(t, m.AssertCalled(r, "RunLocalCommand", cmd))
+	assert.True(t, m.AssertNotCalled(r, "Login"))
+	assert.Empty(t, r.errors)
+}
+
+func TestToken(t *testing.T) {
+	m := New()
+	m.On("Token").Return("abc")
+	m.On("SetToken", "def").Once()
+
+	var auth cherrypy.Authenticator = m
+	auth.SetToken("def")
+
+	assert.Equal(t, "abc", auth.Token())
+	assert.True(t, m.AssertCalled(t, "SetToken", "def"))
+	assert.True(t, m.AssertExpectations(t))
+}
+
+func TestAssertExpectations(t *testing.T) {
+	m := New()
+	m.On("Minions").Return([]cherrypy.Minion{{ID: "web1"}}, nil).Times(2)
+	m.On("Login").Return(nil)
+
+	_, err := m.Minions(context.Background())
+	assert.NoError(t, err)
+
+	r := &recorder{}
+	assert.False(t, m.AssertExpectations(r))
+	assert.Equal(t, []string{
+		"cherrypymock: expected Minions() to be called 2 time(s), called 1 time(s)",
+		"cherrypymock: expected Login() to be called",
+	}, r.errors)
+
+	r = &recorder{}
+	assert.False(t, m.AssertCalled(r, "Minion", "web1"))
+	assert.False(t, m.AssertNotCalled(r, "Minions"))
+	assert.Len(t, r.errors, 2)
+}
+
+func TestRunBatch(t *testing.T) {
+	m := New()
+	m.On("RunBatch").Return([]cherrypy.LocalResult{
+		{"web1": {Return: true}},
+		{"web2": {Return: true}},
+	}, nil)
+
+	var ids []string
+	err := m.RunBatch(context.Background(), cherrypy.Command{Function: "test.ping"}, cherrypy.BatchOptions{}, func(res cherrypy.LocalResult) error {
+		for id := range res {
+			ids = append(ids, id)
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web1", "web2"}, ids)
+}
+
+func TestEvents(t *testing.T) {
+	m := New()
+	ch := make(chan cherrypy.Event, 1)
+	ch <- cherrypy.Event{Tag: "salt/auth"}
+	close(ch)
+
+	m.On("Events").Return(ch, nil)
+
+	events, err := m.Events(context.Background())
+	assert.NoError(t, err)
+	if assert.NotNil(t, events) {
+		assert.Equal(t, "salt/auth", (<-events).Tag)
+	}
+}
+
+func TestConcurrentCalls(t *testing.T) {
+	m := New()
+	m.On("Minion", Anything).Return(&cherrypy.Minion{ID: "web1"}, nil)
+
+	done := make(chan struct{})
+	for i := 0; i < 10; i++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			m.Minion(context.Background(), "web1")
+		}()
+	}
+
+	for i := 0; i < 10; i++ {
+		<-done
+	}
+
+	assert.Len(t, m.CallsTo("Minion"), 10)
+}
@@ -0,0 +1,229 @@
+package cherrypytest
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/finarfin/go-salt-netapi-client/cherrypy"
+)
+
+const (
+	// jobTimeLayout is the layout of StartTime in the job cache
+	jobTimeLayout = "2006, Jan 02 15:04:05.000000"
+
+	// jobQueryTimeLayout is the layout of start_time and end_time accepted by jobs.list_jobs
+	jobQueryTimeLayout = "2006-01-02T15:04:05"
+)
+
+// job is an entry of the job cache; returns are guarded by the lock of the master
+type job struct {
+	jid        string
+	function   string
+	arguments  []interface{}
+	target     interface{}
+	targetType cherrypy.TargetType
+	user       string
+	startTime  time.Time
+	minions    []string
+	returns    map[string]Return
+}
+
+// startJob adds a job run with the local client to the job cache
+func (m *Master) startJob(function string, args []interface{}, kwargs map[string]interface{}, target cherrypy.Target, minions []string) *job {
+	arguments := append([]interface{}{}, args...)
+	if len(kwargs) > 0 {
+		kw := map[string]interface{}{"__kwarg__": true}
+		for k, v := range kwargs {
+			kw[k] = v
+		}
+
+		arguments = append(arguments, kw)
+	}
+
+	j := &job{
+		jid:        m.newJID(),
+		function:   function,
+		arguments:  arguments,
+		target:     target.GetTarget(),
+		targetType: target.GetType(),
+		user:       m.username,
+		startTime:  time.Now().UTC(),
+		minions:    minions,
+		returns:    make(map[string]Return),
+	}
+
+	m.mu.Lock()
+	m.jobs[j.jid] = j
+	m.mu.Unlock()
+
+	return j
+}
+
+func (m *Master) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	jid := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/jobs"), "/")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if jid == "" {
+		jobs := make(map[string]interface{}, len(m.jobs))
+		for id, j := range m.jobs {
+			jobs[id] = j.summary()
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{"return": []interface{}{jobs}})
+		return
+	}
+
+	j, ok := m.jobs[jid]
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"info": []interface{}{map[string]interface{}{
+				"jid":       jid,
+				"Result":    map[string]interface{}{},
+				"StartTime": "",
+				"Error":     "Cannot contact returner or no job with this jid",
+			}},
+			"return": []interface{}{map[string]interface{}{}},
+		})
+		return
+	}
+
+	info := j.summary()
+	info["jid"] = j.jid
+	info["Minions"] = j.minions
+
+	results := make(map[string]interface{}, len(j.returns))
+	returns := make(map[string]interface{}, len(j.returns))
+	for id, ret := range j.returns {
+		results[id] = map[string]interface{}{
+			"return":  ret.Return,
+			"retcode": ret.ReturnCode,
+			"success": ret.success(),
+		}
+		returns[id] = ret.Return
+	}
+
+	info["Result"] = results
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"info":   []interface{}{info},
+		"return": []interface{}{returns},
+	})
+}
+
+// summary returns the job like jobs.list_jobs does
+func (j *job) summary() map[string]interface{} {
+	return map[string]interface{}{
+		"Function":    j.function,
+		"Arguments":   j.arguments,
+		"Target":      j.target,
+		"Target-type": j.targetType,
+		"User":        j.user,
+		"StartTime":   j.startTime.Format(jobTimeLayout),
+	}
+}
+
+/*
+listJobs handles the jobs.list_jobs runner
+
+search_function and search_target are matched with glob patterns;
+start_time and end_time are parsed in the layout sent by Client.QueryJobs().
+*/
+func (m *Master) listJobs(c Call) Return {
+	function, _ := c.KWArgs["search_function"].(string)
+	target, _ := c.KWArgs["search_target"].(string)
+
+	var start, end time.Time
+	for key, t := range map[string]*time.Time{"start_time": &start, "end_time": &end} {
+		s, _ := c.KWArgs[key].(string)
+		if s == "" {
+			continue
+		}
+
+		v, err := time.Parse(jobQueryTimeLayout, s)
+		if err != nil {
+			return Return{Return: err.Error(), ReturnCode: 1, Failed: true}
+		}
+
+		*t = v
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make(map[string]interface{})
+	for id, j := range m.jobs {
+		if function != "" && !fnmatch(j.function, function) {
+			continue
+		}
+
+		if t, ok := j.target.(string); target != "" && (!ok || !fnmatch(t, target)) {
+			continue
+		}
+
+		if (!start.IsZero() && j.startTime.Before(start)) || (!end.IsZero() && j.startTime.After(end)) {
+			continue
+		}
+
+		jobs[id] = j.summary()
+	}
+
+	return Return{Return: jobs}
+}
+
+// lookupJID handles the jobs.lookup_jid runner which returns the returns of a job per minion
+func (m *Master) lookupJID(c Call) Return {
+	jid, _ := c.KWArgs["jid"].(string)
+	if len(c.Args) > 0 {
+		jid, _ = c.Args[0].(string)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	returns := make(map[string]interface{})
+	if j, ok := m.jobs[jid]; ok {
+		for id, ret := range j.returns {
+			returns[id] = ret.Return
+		}
+	}
+
+	return Return{Return: returns}
+}
+
+// matchingIDs returns sorted IDs matching a glob pattern or a comma separated list like salt-key
+func matchingIDs(ids []string, match string) []string {
+	list := map[string]bool{}
+	if strings.Contains(match, ",") {
+		for _, id := range strings.Split(match, ",") {
+			list[id] = true
+		}
+	}
+
+	matched := []string{}
+	for _, id := range ids {
+		if list[id] || (len(list) == 0 && fnmatch(id, match)) {
+			matched = append(matched, id)
+		}
+	}
+
+	sort.Strings(matched)
+	return matched
+}
+
+// fnmatch matches the name with a shell pattern like Salt does
+func fnmatch(name string, pattern string) bool {
+	var matcher cherrypy.Matcher
+
+	// Glob targets cannot fail
+	ok, _ := matcher.Matches(cherrypy.ExpressionTarget{Expression: pattern, Type: cherrypy.Glob}, cherrypy.Minion{ID: name})
+	return ok
+}
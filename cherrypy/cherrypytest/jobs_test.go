@@ -0,0 +1,69 @@
+package cherrypytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/finarfin/go-salt-netapi-client/cherrypy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobs(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	start := time.Now().Add(-time.Second)
+	for _, cmd := range []cherrypy.Command{
+		{Target: cherrypy.ExpressionTarget{Expression: "web*", Type: cherrypy.Glob}, Function: "test.ping"},
+		{Target: cherrypy.ListTarget{Targets: []string{"db1"}}, Function: "grains.items"},
+	} {
+		_, err := client.RunLocalCommand(context.Background(), cmd)
+		assert.NoError(t, err)
+	}
+
+	jobs, err := client.Jobs(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 2)
+
+	res, err := client.QueryJobs(context.Background(), cherrypy.JobQuery{
+		Function:  "grains.*",
+		StartTime: start,
+	})
+
+	assert.NoError(t, err)
+	if assert.Len(t, res, 1) {
+		assert.Equal(t, "grains.items", res[0].Function)
+		assert.Equal(t, DefaultUsername, res[0].User)
+		assert.Equal(t, &cherrypy.ListTarget{Targets: []string{"db1"}}, res[0].Target)
+		assert.WithinDuration(t, time.Now(), res[0].StartTime, time.Minute)
+
+		lookup, err := client.RunRunnerCommand(context.Background(), cherrypy.Command{
+			Function:            "jobs.lookup_jid",
+			PositionalArguments: []interface{}{res[0].ID},
+		})
+
+		assert.NoError(t, err)
+		if assert.NotNil(t, lookup) {
+			assert.Contains(t, lookup.Return, "db1")
+		}
+	}
+
+	res, err = client.QueryJobs(context.Background(), cherrypy.JobQuery{
+		Target:  "web*",
+		EndTime: start,
+	})
+
+	assert.NoError(t, err)
+	assert.Empty(t, res)
+}
+
+func TestMissingJob(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	_, err := client.Job(context.Background(), "20200202210231414902")
+
+	assert.Equal(t, cherrypy.ErrorJobNotFound, err)
+}
@@ -0,0 +1,317 @@
+package cherrypytest
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/finarfin/go-salt-netapi-client/cherrypy"
+)
+
+// keyStates are the states of minion keys in the order listed by salt-key
+var keyStates = []cherrypy.KeyState{
+	cherrypy.KeyStateAccepted,
+	cherrypy.KeyStatePending,
+	cherrypy.KeyStateRejected,
+	cherrypy.KeyStateDenied,
+}
+
+type keyGenerateRequest struct {
+	ID       string `json:"mid"`
+	KeySize  int    `json:"keysize"`
+	Force    bool   `json:"force"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	EAuth    string `json:"eauth"`
+}
+
+func (m *Master) handleKeys(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		m.authenticated(m.getKeys)(w, r)
+	case http.MethodPost:
+		m.generateKeyPair(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (m *Master) getKeys(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/keys"), "/")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id == "" {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"return": m.listKeys()})
+		return
+	}
+
+	res := map[string]interface{}{}
+	if state, ok := m.keys[id]; ok {
+		res[string(state)] = map[string]string{id: fingerprint(id)}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"return": res})
+}
+
+/*
+generateKeyPair generates a key-pair for the minion and accepts it
+
+Credentials are sent in the body instead of a token. Keys are random data in PEM blocks, not real RSA keys.
+An empty archive is returned if the key exists and force is not set.
+*/
+func (m *Master) generateKeyPair(w http.ResponseWriter, r *http.Request) {
+	var req keyGenerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Could not parse request body")
+		return
+	}
+
+	if !m.validCredentials(req.Username, req.Password, req.EAuth) {
+		writeError(w, http.StatusUnauthorized, "Could not authenticate using provided credentials")
+		return
+	}
+
+	if req.ID == "" {
+		writeError(w, http.StatusBadRequest, "Missing mid")
+		return
+	}
+
+	m.mu.Lock()
+	_, exists := m.keys[req.ID]
+	if !exists || req.Force {
+		m.keys[req.ID] = cherrypy.KeyStateAccepted
+	}
+	m.mu.Unlock()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if !exists || req.Force {
+		for name, block := range map[string]string{"minion.pub": "PUBLIC KEY", "minion.pem": "RSA PRIVATE KEY"} {
+			content := fakePEM(block)
+			tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+			tw.Write([]byte(content))
+		}
+	}
+	tw.Close()
+
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"saltkeys-%s.tar\"", req.ID))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// builtinWheel returns the handler of a built-in wheel function; nil if there is none
+func (m *Master) builtinWheel(function string) Handler {
+	switch function {
+	case "key.list_all":
+		return func(c Call) Return {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+
+			return Return{Return: m.listKeys()}
+		}
+	case "key.accept":
+		return m.keyAction(cherrypy.KeyStateAccepted, "include_rejected", "include_denied")
+	case "key.reject":
+		return m.keyAction(cherrypy.KeyStateRejected, "include_accepted", "include_denied")
+	case "key.accept_dict":
+		return m.acceptKeyDict
+	case "key.delete":
+		return func(c Call) Return {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+
+			for _, id := range matchingIDs(m.keyIDs(), matchArg(c)) {
+				delete(m.keys, id)
+			}
+
+			return Return{Return: map[string]interface{}{}}
+		}
+	case "key.name_match":
+		return func(c Call) Return {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+
+			res := map[string][]string{}
+			for _, id := range matchingIDs(m.keyIDs(), matchArg(c)) {
+				state := string(m.keys[id])
+				res[state] = append(res[state], id)
+			}
+
+			return Return{Return: res}
+		}
+	case "key.finger":
+		return func(c Call) Return {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+
+			res := map[string]map[string]string{}
+			for _, id := range matchingIDs(m.keyIDs(), matchArg(c)) {
+				state := string(m.keys[id])
+				if res[state] == nil {
+					res[state] = map[string]string{}
+				}
+
+				res[state][id] = fingerprint(id)
+			}
+
+			return Return{Return: res}
+		}
+	case "minions.connected":
+		return func(c Call) Return {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+
+			ids := []string{}
+			for id, mn := range m.minions {
+				if m.keys[id] == cherrypy.KeyStateAccepted && !mn.offline {
+					ids = append(ids, id)
+				}
+			}
+
+			sort.Strings(ids)
+			return Return{Return: ids}
+		}
+	}
+
+	return nil
+}
+
+/*
+keyAction moves matching pending keys to the state; keys in other states are moved
+if the keyword arguments for them are set (e.g.: include_rejected for key.accept)
+*/
+func (m *Master) keyAction(to cherrypy.KeyState, includes ...string) Handler {
+	return func(c Call) Return {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		from := m.includedStates(c, includes)
+		changed := []string{}
+		for _, id := range matchingIDs(m.keyIDs(), matchArg(c)) {
+			if from[m.keys[id]] {
+				m.keys[id] = to
+				changed = append(changed, id)
+			}
+		}
+
+		return Return{Return: map[string][]string{string(to): changed}}
+	}
+}
+
+// acceptKeyDict accepts keys listed per state in the match keyword argument
+func (m *Master) acceptKeyDict(c Call) Return {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	match, _ := c.KWArgs["match"].(map[string]interface{})
+	from := m.includedStates(c, []string{"include_rejected", "include_denied"})
+
+	changed := []string{}
+	for state, ids := range match {
+		list, _ := ids.([]interface{})
+		for _, v := range list {
+			id, _ := v.(string)
+			if current, ok := m.keys[id]; ok && string(current) == state && from[current] {
+				m.keys[id] = cherrypy.KeyStateAccepted
+				changed = append(changed, id)
+			}
+		}
+	}
+
+	sort.Strings(changed)
+	return Return{Return: map[string][]string{string(cherrypy.KeyStateAccepted): changed}}
+}
+
+// includedStates returns pending and other states enabled with keyword arguments
+func (m *Master) includedStates(c Call, includes []string) map[cherrypy.KeyState]bool {
+	states := map[cherrypy.KeyState]bool{cherrypy.KeyStatePending: true}
+	for _, include := range includes {
+		if c.KWArgs[include] != true {
+			continue
+		}
+
+		switch include {
+		case "include_accepted":
+			states[cherrypy.KeyStateAccepted] = true
+		case "include_rejected":
+			states[cherrypy.KeyStateRejected] = true
+		case "include_denied":
+			states[cherrypy.KeyStateDenied] = true
+		}
+	}
+
+	return states
+}
+
+// listKeys returns keys per state like key.list_all; must be called with the lock held
+func (m *Master) listKeys() map[string][]string {
+	res := map[string][]string{
+		"local": {"master.pem", "master.pub"},
+	}
+
+	for _, state := range keyStates {
+		res[string(state)] = []string{}
+	}
+
+	for _, id := range m.keyIDs() {
+		state := string(m.keys[id])
+		res[state] = append(res[state], id)
+	}
+
+	return res
+}
+
+// keyIDs returns sorted IDs of all minion keys; must be called with the lock held
+func (m *Master) keyIDs() []string {
+	ids := make([]string, 0, len(m.keys))
+	for id := range m.keys {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+	return ids
+}
+
+// matchArg returns the match argument of key functions sent as keyword or the first positional argument
+func matchArg(c Call) string {
+	if match, ok := c.KWArgs["match"].(string); ok {
+		return match
+	}
+
+	if len(c.Args) > 0 {
+		match, _ := c.Args[0].(string)
+		return match
+	}
+
+	return ""
+}
+
+// fingerprint returns a stable fake fingerprint of the minion key
+func fingerprint(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+
+	return strings.Join(parts, ":")
+}
+
+func fakePEM(block string) string {
+	b := make([]byte, 48)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return fmt.Sprintf("-----BEGIN %s-----\n%s\n-----END %s-----\n", block, base64.StdEncoding.EncodeToString(b), block)
+}
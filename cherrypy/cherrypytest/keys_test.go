@@ -0,0 +1,125 @@
+package cherrypytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/finarfin/go-salt-netapi-client/cherrypy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeys(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	master.AddKey("new1", cherrypy.KeyStatePending)
+	master.AddKey("new2", cherrypy.KeyStatePending)
+	master.AddKey("old1", cherrypy.KeyStateRejected)
+
+	keys, err := client.Keys(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, &cherrypy.KeyResult{
+		Local:           []string{"master.pem", "master.pub"},
+		Minions:         []string{"db1", "web1", "web2"},
+		MinionsPre:      []string{"new1", "new2"},
+		MinionsRejected: []string{"old1"},
+		MinionsDenied:   []string{},
+	}, keys)
+
+	fingerprint, err := client.Key(context.Background(), "web1")
+
+	assert.NoError(t, err)
+	assert.Len(t, fingerprint, 95)
+
+	_, err = client.Key(context.Background(), "missing")
+
+	assert.True(t, errors.Is(err, cherrypy.ErrorMinionKeyNotFound))
+}
+
+func TestKeyActions(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	master.AddKey("new1", cherrypy.KeyStatePending)
+	master.AddKey("new2", cherrypy.KeyStatePending)
+	master.AddKey("old1", cherrypy.KeyStateRejected)
+
+	changes, err := client.AcceptKeys(context.Background(), "new1,old1", cherrypy.KeyActionOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []cherrypy.KeyChange{
+		{ID: "new1", From: cherrypy.KeyStatePending, To: cherrypy.KeyStateAccepted},
+	}, changes)
+
+	changes, err = client.RejectKeys(context.Background(), "web*", cherrypy.KeyActionOptions{IncludeAccepted: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []cherrypy.KeyChange{
+		{ID: "web1", From: cherrypy.KeyStateAccepted, To: cherrypy.KeyStateRejected},
+		{ID: "web2", From: cherrypy.KeyStateAccepted, To: cherrypy.KeyStateRejected},
+	}, changes)
+
+	changes, err = client.AcceptKeyDict(context.Background(), cherrypy.KeyResult{
+		MinionsRejected: []string{"web1"},
+		MinionsPre:      []string{"new2"},
+	}, cherrypy.KeyActionOptions{IncludeRejected: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []cherrypy.KeyChange{
+		{ID: "new2", From: cherrypy.KeyStatePending, To: cherrypy.KeyStateAccepted},
+		{ID: "web1", From: cherrypy.KeyStateRejected, To: cherrypy.KeyStateAccepted},
+	}, changes)
+
+	changes, err = client.DeleteKeys(context.Background(), "*1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []cherrypy.KeyChange{
+		{ID: "db1", From: cherrypy.KeyStateAccepted, To: cherrypy.KeyStateDeleted},
+		{ID: "new1", From: cherrypy.KeyStateAccepted, To: cherrypy.KeyStateDeleted},
+		{ID: "old1", From: cherrypy.KeyStateRejected, To: cherrypy.KeyStateDeleted},
+		{ID: "web1", From: cherrypy.KeyStateAccepted, To: cherrypy.KeyStateDeleted},
+	}, changes)
+
+	// Minions without accepted keys are not targeted
+	res, err := client.RunLocalCommand(context.Background(), cherrypy.Command{
+		Target:   cherrypy.ExpressionTarget{Expression: "*", Type: cherrypy.Glob},
+		Function: "test.ping",
+	})
+
+	assert.NoError(t, err)
+	assert.Empty(t, res)
+	assert.Equal(t, cherrypy.KeyStateRejected, master.KeyState("web2"))
+
+	fingerprints, err := client.KeyFingerprints(context.Background(), "web*", "sha256")
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, fingerprints) {
+		assert.Contains(t, fingerprints.MinionsRejected, "web2")
+		assert.Empty(t, fingerprints.Minions)
+	}
+}
+
+func TestGenerateKeyPair(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	keys, err := client.GenerateKeyPair(context.Background(), "new1", 2048, false)
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, keys) {
+		assert.Contains(t, keys.Public, "BEGIN PUBLIC KEY")
+		assert.Contains(t, keys.Private, "BEGIN RSA PRIVATE KEY")
+	}
+
+	assert.Equal(t, cherrypy.KeyStateAccepted, master.KeyState("new1"))
+
+	_, err = client.GenerateKeyPair(context.Background(), "web1", 2048, false)
+
+	assert.True(t, errors.Is(err, cherrypy.ErrorKeyPairNotReceived))
+
+	_, err = client.GenerateKeyPair(context.Background(), "web1", 2048, true)
+
+	assert.NoError(t, err)
+}
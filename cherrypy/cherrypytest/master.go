@@ -0,0 +1,569 @@
+/*
+Package cherrypytest provides a fake Salt master serving the rest_cherrypy API in-process for tests
+
+Minions, grains, keys and jobs are kept in memory. Functions called on minions and the master
+can be scripted with handlers; requests can be failed on purpose to test error handling.
+
+Example usage:
+	master := cherrypytest.NewMaster()
+	defer master.Close()
+
+	master.AddMinion("web1", map[string]interface{}{"os": "Ubuntu"})
+	master.Handle("cmd.run", func(call cherrypytest.Call) cherrypytest.Return {
+		return cherrypytest.Return{Return: "Hello"}
+	})
+
+	client := master.Client()
+	if err := client.Login(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+*/
+package cherrypytest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/finarfin/go-salt-netapi-client/cherrypy"
+)
+
+const (
+	// DefaultUsername is accepted by /login unless WithCredentials() is used
+	DefaultUsername = "saltdev"
+	// DefaultPassword is accepted by /login unless WithCredentials() is used
+	DefaultPassword = "saltdev"
+	// DefaultEAuth is accepted by /login unless WithCredentials() is used
+	DefaultEAuth = "pam"
+
+	defaultTokenLifetime = 12 * time.Hour
+)
+
+// Option configures the master created by NewMaster()
+type Option func(*Master)
+
+// WithCredentials sets credentials accepted by the master
+func WithCredentials(username string, password string, eauth string) Option {
+	return func(m *Master) {
+		m.username = username
+		m.password = password
+		m.eauth = eauth
+	}
+}
+
+// WithTokenLifetime sets how long tokens issued by /login are accepted
+func WithTokenLifetime(d time.Duration) Option {
+	return func(m *Master) {
+		m.tokenLifetime = d
+	}
+}
+
+// Call describes a function called on a minion or on the master (runner and wheel functions)
+type Call struct {
+	// Minion is the ID of the minion running the function; empty for runner and wheel functions
+	Minion   string
+	Function string
+	Args     []interface{}
+	KWArgs   map[string]interface{}
+
+	// Grains of the minion running the function
+	Grains cherrypy.Grains
+
+	// User who started the job
+	User string
+}
+
+// Return is the result of a function returned by a Handler
+type Return struct {
+	Return     interface{}
+	ReturnCode int
+
+	// Failed marks the return unsuccessful regardless of ReturnCode (e.g.: an exception was raised)
+	Failed bool
+
+	// NoResponse drops the return as if the minion did not respond in time; ignored for master functions
+	NoResponse bool
+}
+
+// Handler runs a function on a minion or on the master; handlers may be called concurrently
+type Handler func(Call) Return
+
+// HookEvent is an event fired with the /hook endpoint
+type HookEvent struct {
+	Tag  string
+	Data interface{}
+}
+
+type minion struct {
+	grains  map[string]interface{}
+	offline bool
+}
+
+/*
+Master is an in-process fake Salt master serving the rest_cherrypy API
+
+Following endpoints are implemented: /login, /logout, /minions, /jobs, /keys, /run and /hook.
+Targets are evaluated with cherrypy.Matcher; only minions with accepted keys are matched.
+*/
+type Master struct {
+	// URL of the server (e.g.: http://127.0.0.1:51234)
+	URL string
+
+	server        *httptest.Server
+	mux           *http.ServeMux
+	username      string
+	password      string
+	eauth         string
+	tokenLifetime time.Duration
+
+	mu         sync.Mutex
+	tokens     map[string]time.Time
+	minions    map[string]*minion
+	keys       map[string]cherrypy.KeyState
+	pillars    map[string]map[string]interface{}
+	nodeGroups map[string]string
+	functions  map[string]Handler
+	runners    map[string]Handler
+	wheels     map[string]Handler
+	jobs       map[string]*job
+	lastJID    time.Time
+	failures   map[string][]int
+	requests   map[string]int
+	hooks      []HookEvent
+}
+
+// NewMaster starts a fake master; it must be stopped with Close()
+func NewMaster(opts ...Option) *Master {
+	m := &Master{
+		username:      DefaultUsername,
+		password:      DefaultPassword,
+		eauth:         DefaultEAuth,
+		tokenLifetime: defaultTokenLifetime,
+		tokens:        make(map[string]time.Time),
+		minions:       make(map[string]*minion),
+		keys:          make(map[string]cherrypy.KeyState),
+		pillars:       make(map[string]map[string]interface{}),
+		nodeGroups:    make(map[string]string),
+		functions:     make(map[string]Handler),
+		runners:       make(map[string]Handler),
+		wheels:        make(map[string]Handler),
+		jobs:          make(map[string]*job),
+		failures:      make(map[string][]int),
+		requests:      make(map[string]int),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	m.mux = http.NewServeMux()
+	m.mux.HandleFunc("/login", m.handleLogin)
+	m.mux.HandleFunc("/logout", m.authenticated(m.handleLogout))
+	m.mux.HandleFunc("/minions", m.authenticated(m.handleMinions))
+	m.mux.HandleFunc("/minions/", m.authenticated(m.handleMinions))
+	m.mux.HandleFunc("/jobs", m.authenticated(m.handleJobs))
+	m.mux.HandleFunc("/jobs/", m.authenticated(m.handleJobs))
+	m.mux.HandleFunc("/keys", m.handleKeys)
+	m.mux.HandleFunc("/keys/", m.handleKeys)
+	m.mux.HandleFunc("/run", m.handleRun)
+	m.mux.HandleFunc("/hook/", m.authenticated(m.handleHook))
+
+	m.server = httptest.NewServer(http.HandlerFunc(m.serve))
+	m.URL = m.server.URL
+
+	return m
+}
+
+// Close stops the server
+func (m *Master) Close() {
+	m.server.Close()
+}
+
+// Client returns a client using credentials accepted by the master; Login() is not called
+func (m *Master) Client() *cherrypy.Client {
+	return cherrypy.NewClient(m.URL, m.username, m.password, m.eauth, false)
+}
+
+// AddMinion adds a minion with an accepted key; grains of an existing minion are replaced
+func (m *Master) AddMinion(id string, grains map[string]interface{}) {
+	g := make(map[string]interface{}, len(grains)+1)
+	for k, v := range grains {
+		g[k] = v
+	}
+
+	if _, ok := g["id"]; !ok {
+		g["id"] = id
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.minions[id] = &minion{grains: g}
+	m.keys[id] = cherrypy.KeyStateAccepted
+}
+
+// SetOnline changes whether the minion responds; offline minions are targeted but do not return
+func (m *Master) SetOnline(id string, online bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if mn, ok := m.minions[id]; ok {
+		mn.offline = !online
+	}
+}
+
+// SetPillar sets pillar data of the minion used for pillar targets
+func (m *Master) SetPillar(id string, pillar map[string]interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pillars[id] = pillar
+}
+
+// SetNodeGroup defines a nodegroup with a compound expression
+func (m *Master) SetNodeGroup(name string, expr string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nodeGroups[name] = expr
+}
+
+// AddKey adds a minion key in the state (e.g.: a pending key of a minion which is not accepted yet)
+func (m *Master) AddKey(id string, state cherrypy.KeyState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if state == cherrypy.KeyStateDeleted {
+		delete(m.keys, id)
+		return
+	}
+
+	m.keys[id] = state
+}
+
+// KeyState returns the state of the minion key; KeyStateDeleted if the key does not exist
+func (m *Master) KeyState(id string) cherrypy.KeyState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.keys[id]
+}
+
+// Handle sets the handler of a function called on minions; built-in functions can be replaced
+func (m *Master) Handle(function string, h Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.functions[function] = h
+}
+
+// HandleRunner sets the handler of a runner function; built-in functions can be replaced
+func (m *Master) HandleRunner(function string, h Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.runners[function] = h
+}
+
+// HandleWheel sets the handler of a wheel function; built-in functions can be replaced
+func (m *Master) HandleWheel(function string, h Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.wheels[function] = h
+}
+
+/*
+FailRequests fails the next requests to the endpoint with the HTTP status
+
+endpoint is the first segment of the path (e.g.: run, jobs or login).
+Calls are queued; FailRequests("run", 503, 2) fails the next two requests to /run.
+*/
+func (m *Master) FailRequests(endpoint string, status int, count int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := 0; i < count; i++ {
+		m.failures[endpoint] = append(m.failures[endpoint], status)
+	}
+}
+
+// ExpireTokens invalidates all tokens as if the sessions expired on the master
+func (m *Master) ExpireTokens() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tokens = make(map[string]time.Time)
+}
+
+// Requests returns the number of requests received by the endpoint (e.g.: login), including failed ones
+func (m *Master) Requests(endpoint string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.requests[endpoint]
+}
+
+// Hooks returns events fired with the /hook endpoint in order
+func (m *Master) Hooks() []HookEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]HookEvent(nil), m.hooks...)
+}
+
+func (m *Master) serve(w http.ResponseWriter, r *http.Request) {
+	endpoint := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)[0]
+
+	m.mu.Lock()
+	m.requests[endpoint]++
+	status := 0
+	if queued := m.failures[endpoint]; len(queued) > 0 {
+		status = queued[0]
+		m.failures[endpoint] = queued[1:]
+	}
+	m.mu.Unlock()
+
+	if status != 0 {
+		writeError(w, status, "Scripted failure")
+		return
+	}
+
+	m.mux.ServeHTTP(w, r)
+}
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	EAuth    string `json:"eauth"`
+}
+
+func (m *Master) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Could not parse request body")
+		return
+	}
+
+	if !m.validCredentials(req.Username, req.Password, req.EAuth) {
+		writeError(w, http.StatusUnauthorized, "Could not authenticate using provided credentials")
+		return
+	}
+
+	token := newToken()
+	start := time.Now()
+	expire := start.Add(m.tokenLifetime)
+
+	m.mu.Lock()
+	m.tokens[token] = expire
+	m.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"return": []interface{}{
+			map[string]interface{}{
+				"perms":  []string{".*", "@runner", "@wheel", "@jobs"},
+				"start":  unixTime(start),
+				"token":  token,
+				"expire": unixTime(expire),
+				"user":   req.Username,
+				"eauth":  req.EAuth,
+			},
+		},
+	})
+}
+
+func (m *Master) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	m.mu.Lock()
+	delete(m.tokens, r.Header.Get("X-Auth-Token"))
+	m.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"return": "Your token has been cleared",
+	})
+}
+
+func (m *Master) handleHook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var data interface{}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil && r.ContentLength != 0 {
+		writeError(w, http.StatusBadRequest, "Could not parse request body")
+		return
+	}
+
+	m.mu.Lock()
+	m.hooks = append(m.hooks, HookEvent{
+		Tag:  "salt/netapi" + r.URL.Path,
+		Data: data,
+	})
+	m.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+}
+
+func (m *Master) handleMinions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/minions"), "/")
+
+		m.mu.Lock()
+		minions := make(map[string]interface{})
+		for mid, mn := range m.minions {
+			if id != "" && mid != id {
+				continue
+			}
+
+			// Grains are not returned for offline minions
+			if mn.offline {
+				minions[mid] = false
+			} else {
+				minions[mid] = mn.grains
+			}
+		}
+		m.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"return": []interface{}{minions},
+		})
+	case http.MethodPost:
+		m.submitJobs(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// authenticated rejects requests without a valid token
+func (m *Master) authenticated(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !m.validToken(r.Header.Get("X-Auth-Token")) {
+			writeError(w, http.StatusUnauthorized, "No permission -- at this time.")
+			return
+		}
+
+		h(w, r)
+	}
+}
+
+func (m *Master) validToken(token string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expire, ok := m.tokens[token]
+	if !ok {
+		return false
+	}
+
+	if time.Now().After(expire) {
+		delete(m.tokens, token)
+		return false
+	}
+
+	return true
+}
+
+func (m *Master) validCredentials(username string, password string, eauth string) bool {
+	return username == m.username && password == m.password && eauth == m.eauth
+}
+
+// newJID returns a unique job ID in the format used by Salt (e.g.: 20200202210231414902)
+func (m *Master) newJID() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	if !now.After(m.lastJID) {
+		now = m.lastJID.Add(time.Microsecond)
+	}
+
+	m.lastJID = now
+	return fmt.Sprintf("%s%06d", now.Format("20060102150405"), now.Nanosecond()/1000)
+}
+
+// matcher returns a matcher for minions with accepted keys
+func (m *Master) matcher() *cherrypy.Matcher {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	matcher := &cherrypy.Matcher{
+		Pillars:    make(map[string]map[string]interface{}, len(m.pillars)),
+		NodeGroups: make(map[string]string, len(m.nodeGroups)),
+	}
+
+	for id, mn := range m.minions {
+		if m.keys[id] == cherrypy.KeyStateAccepted {
+			matcher.Minions = append(matcher.Minions, cherrypy.Minion{ID: id, Grains: mn.grains})
+		}
+	}
+
+	for id, p := range m.pillars {
+		matcher.Pillars[id] = p
+	}
+
+	for name, expr := range m.nodeGroups {
+		matcher.NodeGroups[name] = expr
+	}
+
+	return matcher
+}
+
+func newToken() string {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func unixTime(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error page like CherryPy does
+func writeError(w http.ResponseWriter, status int, message string) {
+	title := fmt.Sprintf("%d %s", status, http.StatusText(status))
+
+	w.Header().Set("Content-Type", "text/html;charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<!DOCTYPE html PUBLIC
+"-//W3C//DTD XHTML 1.0 Transitional//EN"
+"http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
+<html>
+<head>
+    <meta http-equiv="Content-Type" content="text/html; charset=utf-8"></meta>
+    <title>%s</title>
+</head>
+    <body>
+        <h2>%s</h2>
+        <p>%s</p>
+        <pre id="traceback"></pre>
+    </body>
+</html>
+`, title, title, html.EscapeString(message))
+}
@@ -0,0 +1,181 @@
+package cherrypytest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/finarfin/go-salt-netapi-client/cherrypy"
+	"github.com/stretchr/testify/assert"
+)
+
+func setup(t *testing.T) (*Master, *cherrypy.Client) {
+	master := NewMaster()
+	master.AddMinion("web1", map[string]interface{}{
+		"os":   "Ubuntu",
+		"ipv4": []interface{}{"127.0.0.1", "10.0.1.11"},
+	})
+	master.AddMinion("web2", map[string]interface{}{
+		"os":   "Ubuntu",
+		"ipv4": []interface{}{"127.0.0.1", "10.0.1.12"},
+	})
+	master.AddMinion("db1", map[string]interface{}{
+		"os":   "CentOS",
+		"ipv4": []interface{}{"127.0.0.1", "10.0.2.11"},
+	})
+
+	client := master.Client()
+	if err := client.Login(context.Background()); err != nil {
+		master.Close()
+		t.Fatal(err)
+	}
+
+	return master, client
+}
+
+func TestLoginLogout(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	session := client.Session()
+	if assert.NotNil(t, session) {
+		assert.Equal(t, DefaultUsername, session.User)
+		assert.Equal(t, DefaultEAuth, session.Backend)
+		assert.True(t, session.ExpireTime.After(session.StartTime))
+	}
+
+	assert.NoError(t, client.Logout(context.Background()))
+	assert.Equal(t, cherrypy.ErrorNotAuthenticated, client.Logout(context.Background()))
+}
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	master := NewMaster(WithCredentials("admin", "secret", "ldap"))
+	defer master.Close()
+
+	client := cherrypy.NewClient(master.URL, "admin", "wrong", "ldap", false)
+
+	assert.True(t, errors.Is(client.Login(context.Background()), cherrypy.ErrorInvalidCredentials))
+	assert.NoError(t, master.Client().Login(context.Background()))
+}
+
+func TestUnauthenticatedRequest(t *testing.T) {
+	master := NewMaster()
+	defer master.Close()
+
+	_, err := master.Client().Minions(context.Background())
+
+	var rerr *cherrypy.RequestError
+	if assert.True(t, errors.As(err, &rerr), "%v", err) {
+		assert.Equal(t, http.StatusUnauthorized, rerr.StatusCode)
+	}
+}
+
+func TestExpiredToken(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	master.ExpireTokens()
+	res, err := client.Minions(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, res, 3)
+	assert.Equal(t, 2, master.Requests("login"))
+}
+
+func TestExpiredTokenConcurrentRequests(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	master.ExpireTokens()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Minions(context.Background())
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, 2, master.Requests("login"))
+}
+
+func TestTokenLifetime(t *testing.T) {
+	master := NewMaster(WithTokenLifetime(time.Hour))
+	defer master.Close()
+
+	client := master.Client()
+	assert.NoError(t, client.Login(context.Background()))
+
+	session := client.Session()
+	if assert.NotNil(t, session) {
+		assert.InDelta(t, time.Hour.Seconds(), session.ExpireTime.Sub(session.StartTime).Seconds(), 1)
+	}
+}
+
+func TestFailRequests(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	master.FailRequests("minions", http.StatusInternalServerError, 1)
+
+	_, err := client.Minions(context.Background())
+
+	var rerr *cherrypy.RequestError
+	if assert.True(t, errors.As(err, &rerr), "%v", err) {
+		assert.Equal(t, http.StatusInternalServerError, rerr.StatusCode)
+		assert.Contains(t, string(rerr.Body), "Scripted failure")
+	}
+
+	_, err = client.Minions(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, master.Requests("minions"))
+}
+
+func TestMinions(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	master.SetOnline("db1", false)
+
+	web1, err := client.Minion(context.Background(), "web1")
+	assert.NoError(t, err)
+	if assert.NotNil(t, web1) {
+		assert.Equal(t, "Ubuntu", web1.Grains.Core().OS)
+		assert.Equal(t, "web1", web1.Grains.Core().ID)
+	}
+
+	db1, err := client.Minion(context.Background(), "db1")
+	assert.NoError(t, err)
+	if assert.NotNil(t, db1) {
+		assert.Nil(t, db1.Grains)
+	}
+
+	_, err = client.Minion(context.Background(), "missing")
+	assert.True(t, errors.Is(err, cherrypy.ErrorMinionNotFound))
+}
+
+func TestHook(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	err := client.Hook(context.Background(), "deploy/web", map[string]interface{}{"version": "1.2"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []HookEvent{
+		{Tag: "salt/netapi/hook/deploy/web", Data: map[string]interface{}{"version": "1.2"}},
+	}, master.Hooks())
+}
@@ -0,0 +1,481 @@
+package cherrypytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/finarfin/go-salt-netapi-client/cherrypy"
+)
+
+// stampLayout is the layout of _stamp fields in returns of master functions
+const stampLayout = "2006-01-02T15:04:05.000000"
+
+// lowstateKeys are not passed as keyword arguments to runner and wheel functions
+var lowstateKeys = map[string]bool{
+	"client":      true,
+	"tgt":         true,
+	"tgt_type":    true,
+	"expr_form":   true,
+	"fun":         true,
+	"arg":         true,
+	"kwarg":       true,
+	"username":    true,
+	"password":    true,
+	"eauth":       true,
+	"token":       true,
+	"full_return": true,
+	"timeout":     true,
+	"ret":         true,
+	"batch":       true,
+	"batch_wait":  true,
+}
+
+// requestError is sent to the client as an error page with the status
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &requestError{status: http.StatusBadRequest, message: fmt.Sprintf(format, args...)}
+}
+
+// notAvailable is returned for functions without a handler like Salt does
+func notAvailable(function string) Return {
+	return Return{Return: fmt.Sprintf("'%s' is not available.", function), ReturnCode: 1, Failed: true}
+}
+
+// builtinFunctions are used for minion functions without a handler set by Handle()
+var builtinFunctions = map[string]Handler{
+	"test.ping": func(c Call) Return {
+		return Return{Return: true}
+	},
+	"test.echo": func(c Call) Return {
+		if len(c.Args) == 0 {
+			return Return{Return: "echo() missing 1 required positional argument: 'text'", ReturnCode: 1, Failed: true}
+		}
+
+		return Return{Return: c.Args[0]}
+	},
+	"grains.items": func(c Call) Return {
+		return Return{Return: c.Grains}
+	},
+	"grains.get": func(c Call) Return {
+		if len(c.Args) == 0 {
+			return Return{Return: "get() missing 1 required positional argument: 'key'", ReturnCode: 1, Failed: true}
+		}
+
+		key, _ := c.Args[0].(string)
+		if v, ok := c.Grains.Get(key); ok {
+			return Return{Return: v}
+		}
+
+		if len(c.Args) > 1 {
+			return Return{Return: c.Args[1]}
+		}
+
+		return Return{Return: ""}
+	},
+}
+
+func (m *Master) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	chunks, err := decodeLowstate(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Credentials sent with each lowstate are accepted in place of a token
+	tokenOK := m.validToken(r.Header.Get("X-Auth-Token"))
+	for _, low := range chunks {
+		username, _ := low["username"].(string)
+		password, _ := low["password"].(string)
+		eauth, _ := low["eauth"].(string)
+
+		if !tokenOK && !m.validCredentials(username, password, eauth) {
+			writeError(w, http.StatusUnauthorized, "No permission -- at this time.")
+			return
+		}
+	}
+
+	results := []interface{}{}
+	for _, low := range chunks {
+		res, err := m.run(low)
+		if err != nil {
+			writeRequestError(w, err)
+			return
+		}
+
+		results = append(results, res...)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"return": results})
+}
+
+// submitJobs handles POST /minions which runs the lowstates with the local_async client
+func (m *Master) submitJobs(w http.ResponseWriter, r *http.Request) {
+	chunks, err := decodeLowstate(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	results := []interface{}{}
+	links := []interface{}{}
+	for _, low := range chunks {
+		low["client"] = string(cherrypy.LocalAsyncClient)
+		res, err := m.run(low)
+		if err != nil {
+			writeRequestError(w, err)
+			return
+		}
+
+		results = append(results, res...)
+		if jid, ok := res[0].(map[string]interface{})["jid"]; ok {
+			links = append(links, map[string]interface{}{"href": fmt.Sprintf("/jobs/%s", jid)})
+		}
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"return": results,
+		"_links": map[string]interface{}{"jobs": links},
+	})
+}
+
+// run executes the lowstate; results of local_batch are returned per minion, others as a single item
+func (m *Master) run(low map[string]interface{}) ([]interface{}, error) {
+	client, _ := low["client"].(string)
+	function, _ := low["fun"].(string)
+	if function == "" {
+		return nil, badRequest("Missing fun in lowstate")
+	}
+
+	switch cherrypy.CommandClient(client) {
+	case cherrypy.LocalClient, cherrypy.LocalAsyncClient, cherrypy.LocalBatchClient:
+		return m.runLocal(cherrypy.CommandClient(client), function, low)
+	case cherrypy.RunnerClient, cherrypy.RunnerAsyncClient, cherrypy.WheelClient, cherrypy.WheelAsyncClient:
+		return m.runMaster(cherrypy.CommandClient(client), function, low)
+	default:
+		return nil, badRequest("Unsupported client: %q", client)
+	}
+}
+
+func (m *Master) runLocal(client cherrypy.CommandClient, function string, low map[string]interface{}) ([]interface{}, error) {
+	target, err := lowstateTarget(low)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := m.matcher().Match(target)
+	if err != nil {
+		return nil, badRequest("%s", err)
+	}
+
+	args, kwargs := lowstateArgs(low, false)
+	j := m.startJob(function, args, kwargs, target, ids)
+
+	if client == cherrypy.LocalAsyncClient {
+		if len(ids) == 0 {
+			return []interface{}{map[string]interface{}{}}, nil
+		}
+
+		res := []interface{}{map[string]interface{}{"jid": j.jid, "minions": ids}}
+		m.runMinions(j, args, kwargs)
+		return res, nil
+	}
+
+	returns := m.runMinions(j, args, kwargs)
+
+	if client == cherrypy.LocalBatchClient {
+		ids := make([]string, 0, len(returns))
+		for id := range returns {
+			ids = append(ids, id)
+		}
+
+		sort.Strings(ids)
+		res := []interface{}{}
+		for _, id := range ids {
+			res = append(res, map[string]interface{}{id: returns[id].Return})
+		}
+
+		return res, nil
+	}
+
+	fullReturn, _ := low["full_return"].(bool)
+	res := make(map[string]interface{}, len(returns))
+	for id, ret := range returns {
+		if !fullReturn {
+			res[id] = ret.Return
+			continue
+		}
+
+		res[id] = map[string]interface{}{
+			"jid":     j.jid,
+			"ret":     ret.Return,
+			"retcode": ret.ReturnCode,
+			"success": ret.success(),
+		}
+	}
+
+	return []interface{}{res}, nil
+}
+
+// runMinions runs the function of the job on targeted minions which are online and stores their returns
+func (m *Master) runMinions(j *job, args []interface{}, kwargs map[string]interface{}) map[string]Return {
+	returns := make(map[string]Return)
+	for _, id := range j.minions {
+		m.mu.Lock()
+		mn := m.minions[id]
+		online := mn != nil && !mn.offline
+		h, ok := m.functions[j.function]
+		if !ok {
+			h = builtinFunctions[j.function]
+		}
+		m.mu.Unlock()
+
+		if !online {
+			continue
+		}
+
+		ret := notAvailable(j.function)
+		if h != nil {
+			// Handlers may use the master; the lock is not held while they run
+			ret = h(Call{
+				Minion:   id,
+				Function: j.function,
+				Args:     args,
+				KWArgs:   kwargs,
+				Grains:   cherrypy.Grains(mn.grains),
+				User:     j.user,
+			})
+		}
+
+		if ret.NoResponse {
+			continue
+		}
+
+		m.mu.Lock()
+		j.returns[id] = ret
+		m.mu.Unlock()
+
+		returns[id] = ret
+	}
+
+	return returns
+}
+
+func (m *Master) runMaster(client cherrypy.CommandClient, function string, low map[string]interface{}) ([]interface{}, error) {
+	args, kwargs := lowstateArgs(low, true)
+	jid := m.newJID()
+
+	prefix, tag := "runner.", "salt/run/"+jid
+	handlers, builtin := m.runners, m.builtinRunner
+	if client == cherrypy.WheelClient || client == cherrypy.WheelAsyncClient {
+		prefix, tag = "wheel.", "salt/wheel/"+jid
+		handlers, builtin = m.wheels, m.builtinWheel
+	}
+
+	m.mu.Lock()
+	h, ok := handlers[function]
+	m.mu.Unlock()
+
+	if !ok {
+		h = builtin(function)
+	}
+
+	ret := notAvailable(function)
+	if h != nil {
+		ret = h(Call{Function: function, Args: args, KWArgs: kwargs, User: m.username})
+	}
+
+	switch client {
+	case cherrypy.RunnerAsyncClient, cherrypy.WheelAsyncClient:
+		// Functions complete before the response; their returns are only available to handlers
+		return []interface{}{map[string]interface{}{"tag": tag, "jid": jid}}, nil
+	case cherrypy.WheelClient:
+		return []interface{}{map[string]interface{}{
+			"tag": tag,
+			"data": map[string]interface{}{
+				"jid":     jid,
+				"return":  ret.Return,
+				"success": ret.success(),
+				"_stamp":  time.Now().UTC().Format(stampLayout),
+				"tag":     tag,
+				"user":    m.username,
+				"fun":     prefix + function,
+			},
+		}}, nil
+	}
+
+	if fullReturn, _ := low["full_return"].(bool); !fullReturn {
+		return []interface{}{ret.Return}, nil
+	}
+
+	funArgs := append([]interface{}{}, args...)
+	if len(kwargs) > 0 {
+		funArgs = append(funArgs, kwargs)
+	}
+
+	return []interface{}{map[string]interface{}{
+		"fun":      prefix + function,
+		"jid":      jid,
+		"user":     m.username,
+		"fun_args": funArgs,
+		"_stamp":   time.Now().UTC().Format(stampLayout),
+		"return":   ret.Return,
+		"success":  ret.success(),
+	}}, nil
+}
+
+// builtinRunner returns the handler of a built-in runner function; nil if there is none
+func (m *Master) builtinRunner(function string) Handler {
+	switch function {
+	case "manage.up", "manage.down":
+		up := function == "manage.up"
+		return func(c Call) Return {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+
+			ids := []string{}
+			for id, mn := range m.minions {
+				if m.keys[id] == cherrypy.KeyStateAccepted && mn.offline != up {
+					ids = append(ids, id)
+				}
+			}
+
+			sort.Strings(ids)
+			return Return{Return: ids}
+		}
+	case "jobs.list_jobs":
+		return m.listJobs
+	case "jobs.lookup_jid":
+		return m.lookupJID
+	}
+
+	return nil
+}
+
+func (r Return) success() bool {
+	return !r.Failed && r.ReturnCode == 0
+}
+
+func decodeLowstate(r *http.Request) ([]map[string]interface{}, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("Could not parse request body: %s", err)
+	}
+
+	// A single lowstate is accepted like Salt does
+	if strings.HasPrefix(strings.TrimSpace(string(raw)), "{") {
+		raw = json.RawMessage("[" + string(raw) + "]")
+	}
+
+	var chunks []map[string]interface{}
+	if err := json.Unmarshal(raw, &chunks); err != nil {
+		return nil, fmt.Errorf("Could not parse lowstate: %s", err)
+	}
+
+	return chunks, nil
+}
+
+func lowstateTarget(low map[string]interface{}) (cherrypy.Target, error) {
+	targetType, _ := low["tgt_type"].(string)
+	if targetType == "" {
+		targetType, _ = low["expr_form"].(string)
+	}
+
+	if targetType == "" {
+		targetType = string(cherrypy.Glob)
+	}
+
+	switch tgt := low["tgt"].(type) {
+	case string:
+		if targetType == cherrypy.List {
+			return cherrypy.ListTarget{Targets: strings.Split(tgt, ",")}, nil
+		}
+
+		return cherrypy.ExpressionTarget{Expression: tgt, Type: cherrypy.TargetType(targetType)}, nil
+	case []interface{}:
+		ids := make([]string, len(tgt))
+		for i, v := range tgt {
+			ids[i], _ = v.(string)
+		}
+
+		return cherrypy.ListTarget{Targets: ids}, nil
+	default:
+		return nil, badRequest("Missing tgt in lowstate")
+	}
+}
+
+/*
+lowstateArgs returns positional and keyword arguments of the lowstate
+
+Dictionaries with __kwarg__ in arg are keyword arguments; args and kwargs are accepted for POST /minions.
+Other keys of the lowstate are keyword arguments of master functions.
+*/
+func lowstateArgs(low map[string]interface{}, master bool) ([]interface{}, map[string]interface{}) {
+	args := []interface{}{}
+	kwargs := make(map[string]interface{})
+
+	for _, key := range []string{"arg", "args"} {
+		switch v := low[key].(type) {
+		case nil:
+		case []interface{}:
+			for _, a := range v {
+				if d, ok := a.(map[string]interface{}); ok && d["__kwarg__"] == true {
+					for k, kv := range d {
+						if k != "__kwarg__" {
+							kwargs[k] = kv
+						}
+					}
+
+					continue
+				}
+
+				args = append(args, a)
+			}
+		default:
+			args = append(args, v)
+		}
+	}
+
+	for _, key := range []string{"kwarg", "kwargs"} {
+		if d, ok := low[key].(map[string]interface{}); ok {
+			for k, v := range d {
+				kwargs[k] = v
+			}
+		}
+	}
+
+	if master {
+		for k, v := range low {
+			if !lowstateKeys[k] {
+				kwargs[k] = v
+			}
+		}
+	}
+
+	return args, kwargs
+}
+
+func writeRequestError(w http.ResponseWriter, err error) {
+	if rerr, ok := err.(*requestError); ok {
+		writeError(w, rerr.status, rerr.message)
+		return
+	}
+
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
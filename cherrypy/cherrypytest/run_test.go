@@ -0,0 +1,274 @@
+package cherrypytest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/finarfin/go-salt-netapi-client/cherrypy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunLocalCommand(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	master.Handle("cmd.run", func(c Call) Return {
+		switch c.Minion {
+		case "web1":
+			return Return{Return: "Hello " + c.Args[0].(string)}
+		case "web2":
+			return Return{Return: "command not found", ReturnCode: 127}
+		default:
+			return Return{NoResponse: true}
+		}
+	})
+
+	res, err := client.RunLocalCommand(context.Background(), cherrypy.Command{
+		Target:              cherrypy.ExpressionTarget{Expression: "*", Type: cherrypy.Glob},
+		Function:            "cmd.run",
+		PositionalArguments: []interface{}{"world"},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+	assert.Equal(t, "Hello world", res["web1"].Return)
+	assert.True(t, res["web1"].Success)
+	assert.Equal(t, 127, res["web2"].ReturnCode)
+	assert.False(t, res["web2"].Success)
+	assert.Equal(t, res["web1"].JID, res["web2"].JID)
+}
+
+func TestRunLocalCommandTargets(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	master.SetPillar("db1", map[string]interface{}{"role": "database"})
+	master.SetNodeGroup("webservers", "web*")
+	master.AddKey("pending1", cherrypy.KeyStatePending)
+
+	cases := []struct {
+		target   cherrypy.Target
+		expected []string
+	}{
+		{cherrypy.ExpressionTarget{Expression: "os:Ubuntu", Type: cherrypy.Grain}, []string{"web1", "web2"}},
+		{cherrypy.ExpressionTarget{Expression: "role:database", Type: cherrypy.Pillar}, []string{"db1"}},
+		{cherrypy.ExpressionTarget{Expression: "10.0.2.0/24", Type: cherrypy.IPCIDR}, []string{"db1"}},
+		{cherrypy.ExpressionTarget{Expression: "N@webservers and not web2", Type: cherrypy.Compound}, []string{"web1"}},
+		{cherrypy.ListTarget{Targets: []string{"web2", "db1", "pending1"}}, []string{"db1", "web2"}},
+	}
+
+	for _, tc := range cases {
+		res, err := client.RunLocalCommand(context.Background(), cherrypy.Command{
+			Target:   tc.target,
+			Function: "test.ping",
+		})
+
+		assert.NoError(t, err, "%v", tc.target)
+
+		ids := []string{}
+		for _, id := range []string{"db1", "web1", "web2", "pending1"} {
+			if r, ok := res[id]; ok {
+				assert.Equal(t, true, r.Return)
+				ids = append(ids, id)
+			}
+		}
+
+		assert.Equal(t, tc.expected, ids, "%v", tc.target)
+	}
+
+	_, err := client.RunLocalCommand(context.Background(), cherrypy.Command{
+		Target:   cherrypy.ExpressionTarget{Expression: "web* and", Type: cherrypy.Compound},
+		Function: "test.ping",
+	})
+
+	var rerr *cherrypy.RequestError
+	if assert.True(t, errors.As(err, &rerr), "%v", err) {
+		assert.Equal(t, http.StatusBadRequest, rerr.StatusCode)
+	}
+}
+
+func TestRunLocalBuiltinFunctions(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	master.SetOnline("web2", false)
+	target := cherrypy.ExpressionTarget{Expression: "web*", Type: cherrypy.Glob}
+
+	res, err := client.RunLocalCommand(context.Background(), cherrypy.Command{
+		Target:              target,
+		Function:            "grains.get",
+		PositionalArguments: []interface{}{"ipv4:1"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, cherrypy.LocalResult{
+		"web1": {JID: res["web1"].JID, Return: "10.0.1.11", Success: true, Outcome: cherrypy.OutcomeSuccess},
+	}, res)
+
+	res, err = client.RunLocalCommand(context.Background(), cherrypy.Command{
+		Target:   target,
+		Function: "state.apply",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "'state.apply' is not available.", res["web1"].Return)
+	assert.False(t, res["web1"].Success)
+}
+
+func TestRunLocalAsyncCommand(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	master.SetOnline("db1", false)
+
+	res, err := client.RunLocalAsyncCommand(context.Background(), cherrypy.Command{
+		Target:      cherrypy.ExpressionTarget{Expression: "*", Type: cherrypy.Glob},
+		Function:    "test.echo",
+		KWArguments: map[string]interface{}{"text": "ignored"},
+		PositionalArguments: []interface{}{
+			"hello",
+		},
+	})
+
+	assert.NoError(t, err)
+	if !assert.NotNil(t, res) {
+		return
+	}
+
+	assert.Equal(t, []string{"db1", "web1", "web2"}, res.Minions)
+
+	job, err := client.Job(context.Background(), res.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "test.echo", job.Function)
+	assert.Equal(t, []interface{}{"hello"}, job.Arguments)
+	assert.Equal(t, map[string]interface{}{"text": "ignored"}, job.KWArguments)
+	assert.Equal(t, map[string]interface{}{"web1": "hello", "web2": "hello"}, job.Returns)
+	assert.Equal(t, []string{"web1", "web2"}, job.Succeeded())
+
+	wait, err := client.WaitForJob(context.Background(), res.ID, cherrypy.WaitOptions{Interval: 10 * time.Millisecond, Timeout: 50 * time.Millisecond})
+
+	assert.Error(t, err)
+	if assert.NotNil(t, wait) {
+		assert.Equal(t, []string{"db1"}, wait.Missing)
+	}
+}
+
+func TestSubmitJob(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	res, err := client.SubmitJobs(context.Background(), []cherrypy.MinionJob{
+		{Target: cherrypy.ExpressionTarget{Expression: "db1", Type: cherrypy.Glob}, Function: "test.ping"},
+		{Target: cherrypy.ExpressionTarget{Expression: "missing", Type: cherrypy.Glob}, Function: "test.ping"},
+	})
+
+	assert.NoError(t, err)
+	if assert.Len(t, res, 2) {
+		assert.Equal(t, []string{"db1"}, res[0].Minions)
+		assert.NotEmpty(t, res[0].ID)
+		assert.Empty(t, res[1].ID)
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	var ids []string
+	err := client.RunBatch(context.Background(), cherrypy.Command{
+		Target:   cherrypy.ExpressionTarget{Expression: "*", Type: cherrypy.Glob},
+		Function: "test.ping",
+	}, cherrypy.BatchOptions{Size: cherrypy.BatchCount(1)}, func(res cherrypy.LocalResult) error {
+		for id := range res {
+			ids = append(ids, id)
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db1", "web1", "web2"}, ids)
+}
+
+func TestRunRunnerCommand(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	master.SetOnline("web2", false)
+
+	res, err := client.RunRunnerCommand(context.Background(), cherrypy.Command{Function: "manage.up"})
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, res) {
+		assert.True(t, res.Success)
+		assert.Equal(t, "runner.manage.up", res.Function)
+		assert.Equal(t, []interface{}{"db1", "web1"}, res.Return)
+	}
+
+	master.HandleRunner("state.orchestrate", func(c Call) Return {
+		return Return{Return: map[string]interface{}{"sls": c.Args[0]}, Failed: true}
+	})
+
+	res, err = client.RunRunnerCommand(context.Background(), cherrypy.Command{
+		Function:            "state.orchestrate",
+		PositionalArguments: []interface{}{"orch.deploy"},
+	})
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, res) {
+		assert.False(t, res.Success)
+		assert.Equal(t, map[string]interface{}{"sls": "orch.deploy"}, res.Return)
+	}
+
+	async, err := client.RunRunnerAsyncCommand(context.Background(), cherrypy.Command{Function: "manage.down"})
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, async) {
+		assert.Equal(t, "salt/run/"+async.ID, async.Tag)
+	}
+}
+
+func TestRunWheelCommand(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	master.HandleWheel("config.values", func(c Call) Return {
+		return Return{Return: map[string]interface{}{"user": c.User}}
+	})
+
+	res, err := client.RunWheelCommand(context.Background(), cherrypy.Command{Function: "config.values"})
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, res) {
+		assert.True(t, res.Success)
+		assert.Equal(t, "wheel.config.values", res.Function)
+		assert.Equal(t, "salt/wheel/"+res.JID, res.Tag)
+		assert.Equal(t, map[string]interface{}{"user": DefaultUsername}, res.Return)
+	}
+
+	res, err = client.RunWheelCommand(context.Background(), cherrypy.Command{Function: "minions.connected"})
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, []interface{}{"db1", "web1", "web2"}, res.Return)
+	}
+}
+
+func TestRunUnsupportedClient(t *testing.T) {
+	master, client := setup(t)
+	defer master.Close()
+
+	_, err := client.RunSSHCommand(context.Background(), cherrypy.Command{
+		Target:   cherrypy.ExpressionTarget{Expression: "*", Type: cherrypy.Glob},
+		Function: "test.ping",
+	})
+
+	var rerr *cherrypy.RequestError
+	if assert.True(t, errors.As(err, &rerr), "%v", err) {
+		assert.Equal(t, http.StatusBadRequest, rerr.StatusCode)
+	}
+}
@@ -5,25 +5,15 @@ package cherrypy
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"sync"
+	"time"
 )
 
-type RequestError struct {
-	StatusCode int
-	Status     string
-	Body       []byte
-}
-
-func (e *RequestError) Error() string {
-	return fmt.Sprintf("HTTP request failed: %s", e.Status)
-}
-
 type eauth struct {
 	Username string
 	Password string
@@ -33,6 +23,9 @@ type eauth struct {
 /*
 Client handles communication with NetAPI rest_cherrypy module (https://docs.saltstack.com/en/latest/ref/netapi/all/salt.netapi.rest_cherrypy.html)
 
+Client is safe for concurrent use by multiple goroutines. The token is shared by all requests;
+when it has to be renewed or is rejected by the master, a single login is sent while other requests wait for it.
+
 Example usage:
 	client := cherrypy.NewClient("http://master:8000", "admin", "password", "pam")
 	if err := client.Login(); err != nil {
@@ -43,35 +36,42 @@ Example usage:
 	minion := client.Minion("minion1")
 */
 type Client struct {
-	client  *http.Client
-	eauth   *eauth
-	Address string
-	Token   string
+	client    *http.Client
+	eauth     *eauth
+	timeout   time.Duration
+	userAgent string
+	logger    Logger
+	retry     *RetryPolicy
+	Address   string
+
+	// loginSem is held while logging in to allow a single login in flight
+	loginSem chan struct{}
+
+	// mu guards the token and the session state below
+	mu      sync.RWMutex
+	token   string
+	session *Session
+	renewAt time.Time
+	loginCh chan struct{}
 }
 
 /*
 NewClient creates a new instance of client
   address: URL of the cherrypy instance on a master (e.g.: https://salt-master:8000)
   backend: External authentication (eauth) backend (https://docs.saltstack.com/en/latest/topics/eauth/index.html)
+
+Proxies set in the environment are not used. See NewClientWithOptions() for more configuration options.
 */
 func NewClient(address string, username string, password string, backend string, skipVerify bool) *Client {
-	a := eauth{
-		Username: username,
-		Password: password,
-		Backend:  backend,
-	}
-
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: skipVerify,
-		},
-	}
-
-	return &Client{
-		client:  &http.Client{Transport: tr},
-		eauth:   &a,
-		Address: address,
-	}
+	// Options used here cannot fail
+	c, _ := NewClientWithOptions(address,
+		WithCredentials(username, password, backend),
+		WithInsecureSkipVerify(skipVerify),
+	)
+
+	// Proxies of the environment (e.g.: HTTPS_PROXY) were never used by this constructor
+	c.client.Transport.(*http.Transport).Proxy = nil
+	return c
 }
 
 func (c *Client) newRequest(ctx context.Context, method string, endpoint string, body interface{}) (*http.Request, error) {
@@ -88,7 +88,7 @@ func (c *Client) newRequest(ctx context.Context, method string, endpoint string,
 		}
 	}
 
-	log.Printf("[DEBUG] Creating request for %s", url)
+	c.debug("Creating request", Field{"method", method}, Field{"endpoint", endpoint})
 	req, err := http.NewRequestWithContext(ctx, method, url, buf)
 	if err != nil {
 		return nil, err
@@ -96,43 +96,111 @@ func (c *Client) newRequest(ctx context.Context, method string, endpoint string,
 
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
-	if c.Token != "" {
-		req.Header.Set("X-Auth-Token", c.Token)
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	if token := c.Token(); token != "" {
+		req.Header.Set("X-Auth-Token", token)
 	}
 
 	return req, nil
 }
 
 func (c *Client) do(req *http.Request, v interface{}) (*http.Response, error) {
+	if c.shouldRenewToken() {
+		if err := c.renewToken(req.Context()); err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("X-Auth-Token", c.Token())
+	}
+
+	resp, err := c.send(req, v)
+	if rerr, ok := err.(*RequestError); ok && rerr.StatusCode == http.StatusUnauthorized && req.Header.Get("X-Auth-Token") != "" {
+		// Token might have been expired or revoked on the master; try once more with a new one
+		if err := c.relogin(req.Context(), req.Header.Get("X-Auth-Token"), req.URL.Path); err != nil {
+			return nil, err
+		}
+
+		retry, err := rewindRequest(req)
+		if err != nil {
+			return nil, err
+		}
+
+		retry.Header.Set("X-Auth-Token", c.Token())
+		return c.send(retry, v)
+	}
+
+	return resp, err
+}
+
+// decodeFunc reads the response body while it is being received instead of decoding it at once
+type decodeFunc func(*json.Decoder) error
+
+func (c *Client) sendOnce(req *http.Request, v interface{}) (*http.Response, error) {
+	resp, _, err := c.attempt(req, v)
+	return resp, err
+}
+
+/*
+attempt sends the request once and reports whether a failure may be retried
+
+Only transport errors and status codes listed by the retry policy are retryable;
+errors decoding the response or returned by callbacks reading it are not, as the response was already processed.
+*/
+func (c *Client) attempt(req *http.Request, v interface{}) (*http.Response, bool, error) {
+	if c.timeout > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), c.timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+
+	start := time.Now()
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, err
+		c.warn("Request failed", Field{"method", req.Method}, Field{"endpoint", req.URL.Path}, Field{"error", err}, Field{"duration", time.Since(start)})
+		return nil, c.retry.transient(err), err
 	}
 
 	defer resp.Body.Close()
 
-	log.Printf("[DEBUG] Received response %s from %s", resp.Status, resp.Request.URL)
+	c.debug("Received response", Field{"method", req.Method}, Field{"endpoint", req.URL.Path}, Field{"status", resp.StatusCode}, Field{"duration", time.Since(start)})
 	if resp.StatusCode > 299 || resp.StatusCode < 200 {
 		// Not checking for error as it does not matter
 		body, _ := ioutil.ReadAll(resp.Body)
 
-		return nil, &RequestError{
-			Status:     resp.Status,
-			StatusCode: resp.StatusCode,
-			Body:       body,
-		}
+		return nil, c.retry.retryableStatus(resp.StatusCode), newRequestError(resp, body)
 	}
 
 	if v != nil {
 		if w, ok := v.(io.Writer); ok {
 			io.Copy(w, resp.Body)
+		} else if decode, ok := v.(decodeFunc); ok {
+			if err := decode(json.NewDecoder(resp.Body)); err != nil {
+				return nil, false, err
+			}
 		} else {
 			err = json.NewDecoder(resp.Body).Decode(v)
 			if err != nil && err != io.EOF {
-				return nil, err
+				return nil, false, err
 			}
 		}
 	}
 
-	return resp, nil
+	return resp, false, nil
+}
+
+// rewindRequest returns a copy of the request with a fresh body so it can be sent again
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+
+		r.Body = body
+	}
+
+	return r, nil
 }
@@ -3,12 +3,15 @@ package cherrypy
 import (
 	"context"
 	"errors"
-	"log"
+	"time"
 )
 
+// maxRenewalMargin is the longest time before expiry that a token will be renewed
+const maxRenewalMargin = time.Minute
+
 var (
 	// ErrorInvalidCredentials indicates authentication failed with 401 error.
-	// Username, password or backend might be invalid.
+	// Username, password or backend might be invalid. It is matched with errors.Is() on the *RequestError returned.
 	ErrorInvalidCredentials = errors.New("invalid credentials or authentication backend: %s")
 
 	// ErrorNotAuthenticated indicates Logout() was called before authenticating with Salt
@@ -35,39 +38,81 @@ type loginResponse struct {
 	Return []loginData `json:"return"`
 }
 
+// Session contains details of the eauth session established by Login()
+type Session struct {
+	User       string
+	Backend    string
+	StartTime  time.Time
+	ExpireTime time.Time
+}
+
 /*
 Login establishes a session with rest_cherrypy and retrieves the token
 
+Expiry of the token is tracked; requests sent shortly before the token expires
+will log in again automatically. Requests rejected with 401 are retried once after logging in again.
+A single login is sent at a time; concurrent calls wait for the login in flight to finish.
+
 https://docs.saltstack.com/en/latest/ref/netapi/all/salt.netapi.rest_cherrypy.html#login
 */
 func (c *Client) Login(ctx context.Context) error {
+	if err := c.acquireLogin(ctx); err != nil {
+		return err
+	}
+	defer c.releaseLogin()
+
+	return c.login(ctx)
+}
+
+// login must be called with loginSem held
+func (c *Client) login(ctx context.Context) error {
 	data := loginRequest{
 		Username: c.eauth.Username,
 		Password: c.eauth.Password,
 		Backend:  c.eauth.Backend,
 	}
 
-	req, err := c.newRequest(ctx, "POST", "login", data)
+	// Logging in again is harmless; allow retrying on transient errors
+	req, err := c.newRequest(AllowRetry(ctx), "POST", "login", data)
 	if err != nil {
 		return err
 	}
 
-	log.Println("[DEBUG] Sending authentication request")
+	c.debug("Sending authentication request", Field{"user", c.eauth.Username}, Field{"eauth", c.eauth.Backend})
 	var response loginResponse
-	_, err = c.do(req, &response)
+	_, err = c.send(req, &response)
 	if err != nil {
 		if rerr, ok := err.(*RequestError); ok {
 			if rerr.StatusCode == 401 {
-				return ErrorInvalidCredentials
+				rerr.cause = ErrorInvalidCredentials
 			}
 		}
 
 		return err
 	}
 
-	c.Token = response.Return[0].Token
-	log.Printf("[DEBUG] Received token %s", c.Token)
+	d := response.Return[0]
 
+	// Master's clock might differ from ours; only the lifetime of the token is relied on.
+	// Tokens without a lifetime are not renewed, like the ones set with SetToken().
+	var renewAt time.Time
+	if lifetime := d.ExpireTime.Sub(d.StartTime.Time); lifetime > 0 {
+		margin := lifetime / 10
+		if margin > maxRenewalMargin {
+			margin = maxRenewalMargin
+		}
+
+		renewAt = time.Now().Add(lifetime - margin)
+	}
+
+	c.setToken(d.Token, &Session{
+		User:       d.User,
+		Backend:    d.Backend,
+		StartTime:  d.StartTime.Time,
+		ExpireTime: d.ExpireTime.Time,
+	}, renewAt)
+
+	c.log(LogLevelInfo, "Logged in", Field{"user", d.User}, Field{"eauth", d.Backend}, Field{"expire", d.ExpireTime.Time})
 	return nil
 }
 
@@ -75,11 +120,18 @@ func (c *Client) Login(ctx context.Context) error {
 Logout terminates the session with rest_cherrypy and clears the token
 
 Calls to logout will fail with ErrorNotAuthenticated if Login() was not called prior.
+A login in flight (e.g.: renewing the token) finishes before the session is terminated;
+requests rejected afterwards are not retried with a new token.
 
 https://docs.saltstack.com/en/latest/ref/netapi/all/salt.netapi.rest_cherrypy.html#logout
 */
 func (c *Client) Logout(ctx context.Context) error {
-	if c.Token == "" {
+	if err := c.acquireLogin(ctx); err != nil {
+		return err
+	}
+	defer c.releaseLogin()
+
+	if c.Token() == "" {
 		return ErrorNotAuthenticated
 	}
 
@@ -88,12 +140,134 @@ func (c *Client) Logout(ctx context.Context) error {
 		return err
 	}
 
-	log.Println("[DEBUG] Sending logout request")
-	_, err = c.do(req, nil)
+	c.debug("Sending logout request")
+	_, err = c.send(req, nil)
 	if err != nil {
 		return err
 	}
 
-	c.Token = ""
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = ""
+	c.session = nil
+	c.renewAt = time.Time{}
 	return nil
 }
+
+/*
+Session returns details of the current session
+
+nil is returned if Login() was not called or the session was terminated with Logout().
+*/
+func (c *Client) Session() *Session {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.session == nil {
+		return nil
+	}
+
+	s := *c.session
+	return &s
+}
+
+// Token returns the token sent with requests; empty if not authenticated
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.token
+}
+
+/*
+SetToken sets the token sent with requests (e.g.: a token retrieved by another client)
+
+Expiry of the token is unknown; it is not renewed before it expires and Session() returns nil
+until Login() is called. Requests rejected with 401 are still retried after logging in again.
+*/
+func (c *Client) SetToken(token string) {
+	c.setToken(token, nil, time.Time{})
+}
+
+// setToken replaces the token and wakes up subscriptions waiting for a new token
+func (c *Client) setToken(token string, session *Session, renewAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = token
+	c.session = session
+	c.renewAt = renewAt
+
+	notify := c.loginCh
+	c.loginCh = make(chan struct{})
+	if notify != nil {
+		close(notify)
+	}
+}
+
+// acquireLogin waits until no other login is in flight
+func (c *Client) acquireLogin(ctx context.Context) error {
+	select {
+	case c.loginSem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) releaseLogin() {
+	<-c.loginSem
+}
+
+// renewToken logs in again unless another request renewed the token while waiting
+func (c *Client) renewToken(ctx context.Context) error {
+	if err := c.acquireLogin(ctx); err != nil {
+		return err
+	}
+	defer c.releaseLogin()
+
+	if !c.shouldRenewToken() {
+		return nil
+	}
+
+	c.log(LogLevelInfo, "Token is about to expire, logging in again")
+	return c.login(ctx)
+}
+
+/*
+relogin logs in again after the token was rejected unless another request replaced it while waiting
+
+ErrorNotAuthenticated is returned if the token was cleared (e.g.: by Logout()) after the request was sent.
+*/
+func (c *Client) relogin(ctx context.Context, rejected string, endpoint string) error {
+	if err := c.acquireLogin(ctx); err != nil {
+		return err
+	}
+	defer c.releaseLogin()
+
+	switch token := c.Token(); {
+	case token == "":
+		return ErrorNotAuthenticated
+	case token != rejected:
+		return nil
+	}
+
+	c.warn("Token was rejected, logging in again", Field{"endpoint", endpoint})
+	return c.login(ctx)
+}
+
+// loginNotification returns a channel which is closed by the next successful Login() or SetToken()
+func (c *Client) loginNotification() <-chan struct{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.loginCh
+}
+
+func (c *Client) shouldRenewToken() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.token != "" && !c.renewAt.IsZero() && !time.Now().Before(c.renewAt)
+}
@@ -2,8 +2,13 @@ package cherrypy
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"sync"
 	"testing"
+	"time"
 
+	apiTester "github.com/finarfin/go-apiclient-tester/tester"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -12,11 +17,11 @@ func TestValidLogin(t *testing.T) {
 	defer tester.Close()
 	tester.Setup(t, "auth_login", "success")
 
-	c.Token = ""
+	c.SetToken("")
 	err := c.Login(context.Background())
 
 	assert.NoError(t, err)
-	assert.Equal(t, testToken, c.Token)
+	assert.Equal(t, testToken, c.Token())
 }
 
 func TestInvalidLogin(t *testing.T) {
@@ -24,11 +29,19 @@ func TestInvalidLogin(t *testing.T) {
 	defer tester.Close()
 	tester.Setup(t, "auth_login", "bad_user")
 
-	c.Token = ""
+	c.SetToken("")
 	err := c.Login(context.Background())
 
-	assert.Error(t, err)
-	assert.Equal(t, "", c.Token)
+	assert.True(t, errors.Is(err, ErrorInvalidCredentials))
+	assert.True(t, errors.Is(err, ErrorUnauthorized))
+
+	var rerr *RequestError
+	if assert.True(t, errors.As(err, &rerr)) {
+		assert.Equal(t, http.StatusUnauthorized, rerr.StatusCode)
+		assert.Equal(t, ErrorKindUnauthorized, rerr.Kind)
+	}
+
+	assert.Equal(t, "", c.Token())
 }
 
 func TestLogout(t *testing.T) {
@@ -44,5 +57,310 @@ func TestLogout(t *testing.T) {
 	err = c.Logout(context.Background())
 
 	assert.NoError(t, err)
-	assert.Empty(t, c.Token)
+	assert.Empty(t, c.Token())
+	assert.Nil(t, c.Session())
+}
+
+func TestLoginSession(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "auth_login", "success")
+
+	assert.Nil(t, c.Session())
+	err := c.Login(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := c.Session()
+	assert.NotNil(t, s)
+	assert.Equal(t, testUsername, s.User)
+	assert.Equal(t, testEAuth, s.Backend)
+	assert.Equal(t, int64(1580672424), s.StartTime.Unix())
+	assert.Equal(t, int64(1580715624), s.ExpireTime.Unix())
+	assert.True(t, c.renewAt.After(time.Now().Add(11*time.Hour)))
+}
+
+func TestLoginWithoutLifetime(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "stats", "success")
+
+	login, err := tester.Scenario("auth_login", "no_lifetime")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logins := 0
+	tester.Do(login.Request.Path, func(w http.ResponseWriter, req *http.Request) {
+		logins++
+		apiTester.WriteResponse(t, &login.Response, w)
+	})
+
+	err = c.Login(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Stats(context.Background())
+
+	assert.NoError(t, err)
+	assert.True(t, c.renewAt.IsZero())
+	assert.Equal(t, 1, logins)
+}
+
+func TestTokenRenewal(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "auth_login", "success")
+	tester.Setup(t, "stats", "success")
+
+	c.renewAt = time.Now().Add(-time.Second)
+	_, err := c.Stats(context.Background())
+
+	assert.NoError(t, err)
+	assert.NotNil(t, c.Session())
+	assert.True(t, c.renewAt.After(time.Now()))
+}
+
+func TestRetryAfterUnauthorized(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "auth_login", "success")
+
+	stats, err := tester.Scenario("stats", "success")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	tester.Do(stats.Request.Path, func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		apiTester.WriteResponse(t, &stats.Response, w)
+	})
+
+	res, err := c.Stats(context.Background())
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res)
+	assert.Equal(t, 2, calls)
+}
+
+// serveLogins serves successful logins and returns a function reporting how many were received
+func serveLogins(t *testing.T, tester *apiTester.Tester) func() int {
+	login, err := tester.Scenario("auth_login", "success")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	calls := 0
+	tester.Do(login.Request.Path, func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+
+		// Give other requests time to pile up behind the login in flight
+		time.Sleep(10 * time.Millisecond)
+		apiTester.WriteResponse(t, &login.Response, w)
+	})
+
+	return func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return calls
+	}
+}
+
+// statsConcurrently sends stats requests from multiple goroutines and returns their errors
+func statsConcurrently(c *Client, n int) []error {
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = c.Stats(context.Background())
+		}(i)
+	}
+
+	wg.Wait()
+	return errs
+}
+
+func TestConcurrentTokenRenewal(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "stats", "success")
+	logins := serveLogins(t, tester)
+
+	if err := c.Login(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	c.mu.Lock()
+	c.renewAt = time.Now().Add(-time.Second)
+	c.mu.Unlock()
+
+	for _, err := range statsConcurrently(c, 20) {
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, 2, logins())
+	assert.False(t, c.shouldRenewToken())
+}
+
+func TestConcurrentRetryAfterUnauthorized(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	logins := serveLogins(t, tester)
+
+	stats, err := tester.Scenario("stats", "success")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tester.Do(stats.Request.Path, func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Auth-Token") != testToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		apiTester.WriteResponse(t, &stats.Response, w)
+	})
+
+	c.SetToken("revoked")
+	for _, err := range statsConcurrently(c, 20) {
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, 1, logins())
+	assert.Equal(t, testToken, c.Token())
+}
+
+func TestLogoutDuringRelogin(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "auth_logout", "success")
+
+	login, err := tester.Scenario("auth_login", "success")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	var once sync.Once
+	tester.Do(login.Request.Path, func(w http.ResponseWriter, req *http.Request) {
+		once.Do(func() { close(started) })
+
+		// Keep the login in flight while Logout() is called
+		time.Sleep(20 * time.Millisecond)
+		apiTester.WriteResponse(t, &login.Response, w)
+	})
+	tester.Do("/stats", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	c.SetToken("revoked")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Stats(context.Background())
+	}()
+
+	<-started
+	err = c.Logout(context.Background())
+	<-done
+
+	assert.NoError(t, err)
+	assert.Empty(t, c.Token())
+	assert.Nil(t, c.Session())
+}
+
+func TestConcurrentSetToken(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "stats", "success")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			c.SetToken(testToken)
+			c.Session()
+		}
+	}()
+
+	for _, err := range statsConcurrently(c, 20) {
+		assert.NoError(t, err)
+	}
+
+	<-done
+}
+
+func TestLoginCancelledWhileWaiting(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+
+	c.loginSem <- struct{}{}
+	defer c.releaseLogin()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(t, context.Canceled, c.Login(ctx))
+}
+
+func TestConcurrentStreamsAfterUnauthorized(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	logins := serveLogins(t, tester)
+
+	tester.Do("/events", func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Auth-Token") != testToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeEvent(w, "", "salt/job/20200202224725441938/new", testEventJobNew)
+		<-req.Context().Done()
+	})
+	tester.Do("/ws/revoked", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	tester.Do("/ws/"+testToken, serveWebSocket(t, "data: "+testEventJobNew+"\n\n"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c.SetToken("revoked")
+
+	var wg sync.WaitGroup
+	streams := make([]<-chan Event, 2)
+	errs := make([]error, 2)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		streams[0], errs[0] = c.Events(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		streams[1], errs[1] = c.WebSocketEvents(ctx, WebSocketOptions{})
+	}()
+	wg.Wait()
+
+	for i, err := range errs {
+		if assert.NoError(t, err) {
+			assert.Equal(t, "salt/job/20200202224725441938/new", receiveEvent(t, streams[i]).Tag)
+		}
+	}
+
+	assert.Equal(t, 1, logins())
 }
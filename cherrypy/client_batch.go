@@ -0,0 +1,166 @@
+package cherrypy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// LocalBatchClient sends commands to Minions in batches. Equivalent to the salt CLI command with --batch-size flag.
+const LocalBatchClient CommandClient = "local_batch"
+
+var (
+	// ErrorInvalidBatchSize indicates the batch size is not a positive count or a percentage between 1 and 100
+	ErrorInvalidBatchSize = errors.New("invalid batch size")
+)
+
+/*
+BatchSize is the number of minions executing the command at the same time
+
+Use BatchCount() or BatchPercent() to create one.
+*/
+type BatchSize string
+
+// BatchCount returns a batch size of the given number of minions
+func BatchCount(count int) BatchSize {
+	return BatchSize(strconv.Itoa(count))
+}
+
+// BatchPercent returns a batch size of the given percentage of targeted minions
+func BatchPercent(percent int) BatchSize {
+	return BatchSize(strconv.Itoa(percent) + "%")
+}
+
+func (s BatchSize) validate() error {
+	v := string(s)
+	percent := len(v) > 0 && v[len(v)-1] == '%'
+	if percent {
+		v = v[:len(v)-1]
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || (percent && n > 100) {
+		return fmt.Errorf("%w: %q", ErrorInvalidBatchSize, string(s))
+	}
+
+	return nil
+}
+
+// BatchOptions controls how minions are split into batches
+type BatchOptions struct {
+	// Size of each batch as a count or a percentage of targeted minions
+	Size BatchSize
+
+	// Wait is the time to wait after a minion returns before starting the next one. Sent in seconds.
+	Wait time.Duration
+}
+
+/*
+RunBatch runs a command on minions in batches using the local_batch client
+
+Returns are passed to fn as they are received; each call usually contains the return of a single minion.
+Returning an error from fn stops reading the returns and the error is returned from RunBatch.
+Client of the command is ignored.
+
+https://docs.saltstack.com/en/latest/ref/netapi/all/salt.netapi.rest_cherrypy.html#salt.netapi.rest_cherrypy.app.Run
+https://docs.saltstack.com/en/latest/topics/targeting/batch.html
+*/
+func (c *Client) RunBatch(ctx context.Context, cmd Command, opts BatchOptions, fn func(LocalResult) error) error {
+	if err := opts.Size.validate(); err != nil {
+		return err
+	}
+
+	cmd.Client = LocalBatchClient
+	d, err := c.lowstate(cmd)
+	if err != nil {
+		return err
+	}
+
+	d["batch"] = string(opts.Size)
+	if opts.Wait > 0 {
+		d["batch_wait"] = int(math.Ceil(opts.Wait.Seconds()))
+	}
+
+	req, err := c.newRequest(ctx, "POST", "run", []map[string]interface{}{d})
+	if err != nil {
+		return err
+	}
+
+	c.debug("Sending batch job request", Field{"function", cmd.Function}, Field{"batch", opts.Size})
+	_, err = c.do(req, decodeFunc(func(dec *json.Decoder) error {
+		return decodeReturns(dec, func(raw interface{}) error {
+			res, err := ParseLocalResult(raw)
+			if err != nil {
+				return err
+			}
+
+			return fn(res)
+		})
+	}))
+
+	return err
+}
+
+/*
+decodeReturns reads items of the return array one by one
+so each of them can be processed before the whole response is received
+*/
+func decodeReturns(dec *json.Decoder, fn func(interface{}) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		if t != "return" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := expectDelim(dec, '['); err != nil {
+			return err
+		}
+
+		for dec.More() {
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				return err
+			}
+
+			if err := fn(v); err != nil {
+				return err
+			}
+		}
+
+		if err := expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if t != delim {
+		return fmt.Errorf("unexpected token in response: %v", t)
+	}
+
+	return nil
+}
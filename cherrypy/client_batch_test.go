@@ -0,0 +1,114 @@
+package cherrypy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunBatch(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "local_batch_success")
+
+	cmd := Command{
+		Target:              ExpressionTarget{Expression: "minion*", Type: Glob},
+		Function:            "cmd.run",
+		PositionalArguments: []interface{}{"uptime"},
+		Timeout:             30 * time.Second,
+	}
+
+	var minions []string
+	err := c.RunBatch(context.Background(), cmd, BatchOptions{Size: BatchPercent(50), Wait: 2 * time.Second}, func(res LocalResult) error {
+		for id, r := range res {
+			assert.True(t, r.Success)
+			assert.Contains(t, r.Return, "load average")
+			minions = append(minions, id)
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"minion1", "minion2"}, minions)
+}
+
+func TestRunBatchStop(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "local_batch_success")
+
+	cmd := Command{
+		Target:              ExpressionTarget{Expression: "minion*", Type: Glob},
+		Function:            "cmd.run",
+		PositionalArguments: []interface{}{"uptime"},
+		Timeout:             30 * time.Second,
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err := c.RunBatch(context.Background(), cmd, BatchOptions{Size: BatchPercent(50), Wait: 2 * time.Second}, func(res LocalResult) error {
+		calls++
+		return stop
+	})
+
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRunBatchInvalidSize(t *testing.T) {
+	c := NewClient("http://localhost", "test_user", "test_pwd", "pam", false)
+
+	for _, size := range []BatchSize{"", BatchCount(0), BatchPercent(0), BatchPercent(101), "ten"} {
+		err := c.RunBatch(context.Background(), Command{Function: "test.ping"}, BatchOptions{Size: size}, func(LocalResult) error {
+			return nil
+		})
+
+		assert.True(t, errors.Is(err, ErrorInvalidBatchSize), "size %q", size)
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	assert.Equal(t, BatchSize("10"), BatchCount(10))
+	assert.Equal(t, BatchSize("25%"), BatchPercent(25))
+	assert.NoError(t, BatchPercent(100).validate())
+}
+
+func TestRunBatchStopIsNotRetried(t *testing.T) {
+	tester, _ := setup(t)
+	defer tester.Close()
+	calls := setupRetry(t, tester, "run", "local_batch_success", 0)
+
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.RetryNonIdempotent = true
+
+	c, err := NewClientWithOptions(tester.URL,
+		WithCredentials(testUsername, testPassword, testEAuth),
+		WithRetryPolicy(policy),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := Command{
+		Target:              ExpressionTarget{Expression: "minion*", Type: Glob},
+		Function:            "cmd.run",
+		PositionalArguments: []interface{}{"uptime"},
+		Timeout:             30 * time.Second,
+	}
+
+	stop := errors.New("stop")
+	callbacks := 0
+	err = c.RunBatch(context.Background(), cmd, BatchOptions{Size: BatchPercent(50), Wait: 2 * time.Second}, func(res LocalResult) error {
+		callbacks++
+		return stop
+	})
+
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, callbacks)
+	assert.Equal(t, 1, *calls)
+}
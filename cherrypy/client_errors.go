@@ -0,0 +1,220 @@
+package cherrypy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// ErrorKind classifies errors returned by rest_cherrypy
+type ErrorKind string
+
+const (
+	// ErrorKindUnauthorized indicates the token or credentials were rejected
+	ErrorKindUnauthorized ErrorKind = "unauthorized"
+
+	// ErrorKindForbidden indicates the user is not permitted to run the function on the target
+	ErrorKindForbidden ErrorKind = "forbidden"
+
+	// ErrorKindBadRequest indicates the request was invalid (e.g.: SaltInvocationError or a missing function)
+	ErrorKindBadRequest ErrorKind = "bad_request"
+
+	// ErrorKindUnavailable indicates the master did not respond to rest_cherrypy
+	ErrorKindUnavailable ErrorKind = "unavailable"
+
+	// ErrorKindInternal indicates an unexpected error on the master
+	ErrorKindInternal ErrorKind = "internal"
+
+	// ErrorKindUnknown is used for status codes which are not classified
+	ErrorKindUnknown ErrorKind = "unknown"
+)
+
+var (
+	// ErrorUnauthorized matches request errors of ErrorKindUnauthorized with errors.Is()
+	ErrorUnauthorized = errors.New("unauthorized")
+
+	// ErrorForbidden matches request errors of ErrorKindForbidden with errors.Is()
+	ErrorForbidden = errors.New("forbidden")
+
+	// ErrorBadRequest matches request errors of ErrorKindBadRequest with errors.Is()
+	ErrorBadRequest = errors.New("bad request")
+
+	// ErrorMasterUnavailable matches request errors of ErrorKindUnavailable with errors.Is()
+	ErrorMasterUnavailable = errors.New("master unavailable")
+
+	// ErrorInternal matches request errors of ErrorKindInternal with errors.Is()
+	ErrorInternal = errors.New("internal server error")
+)
+
+var errorKinds = map[ErrorKind]error{
+	ErrorKindUnauthorized: ErrorUnauthorized,
+	ErrorKindForbidden:    ErrorForbidden,
+	ErrorKindBadRequest:   ErrorBadRequest,
+	ErrorKindUnavailable:  ErrorMasterUnavailable,
+	ErrorKindInternal:     ErrorInternal,
+}
+
+/*
+saltExceptions classify errors by exceptions of Salt found in tracebacks and messages
+
+https://docs.saltstack.com/en/latest/ref/netapi/all/salt.netapi.rest_cherrypy.html#salt.netapi.rest_cherrypy.app.hypermedia_handler
+*/
+var saltExceptions = []struct {
+	pattern string
+	kind    ErrorKind
+}{
+	{"AuthorizationError", ErrorKindForbidden},
+	{"EauthAuthenticationError", ErrorKindUnauthorized},
+	{"TokenAuthenticationError", ErrorKindUnauthorized},
+	{"AuthenticationError", ErrorKindUnauthorized},
+	{"SaltInvocationError", ErrorKindBadRequest},
+	{"is not available", ErrorKindBadRequest},
+	{"SaltDaemonNotRunning", ErrorKindUnavailable},
+	{"SaltReqTimeoutError", ErrorKindUnavailable},
+	{"SaltClientTimeout", ErrorKindUnavailable},
+}
+
+var (
+	htmlMessagePattern   = regexp.MustCompile(`(?s)<p>(.*?)</p>`)
+	htmlTracebackPattern = regexp.MustCompile(`(?s)<pre id="traceback">(.*?)</pre>`)
+)
+
+/*
+RequestError is returned when rest_cherrypy responds with an error status
+
+Message and Traceback are parsed from the HTML error pages of CherryPy and the JSON bodies of Salt;
+Traceback is only sent if the master runs with debug enabled.
+
+Example usage:
+	_, err := client.RunLocalCommand(ctx, cmd)
+	if errors.Is(err, cherrypy.ErrorForbidden) {
+		// eauth permissions do not allow the function
+	}
+
+	var rerr *cherrypy.RequestError
+	if errors.As(err, &rerr) {
+		log.Println(rerr.Kind, rerr.Message, rerr.Traceback)
+	}
+*/
+type RequestError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+	Kind       ErrorKind
+	Message    string
+	Traceback  string
+
+	// cause is the error of the client the request error is reported as (e.g.: ErrorInvalidCredentials)
+	cause error
+}
+
+func (e *RequestError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("HTTP request failed: %s: %s", e.Status, e.Message)
+	}
+
+	return fmt.Sprintf("HTTP request failed: %s", e.Status)
+}
+
+// Is reports whether the target is the sentinel error of the kind (e.g.: ErrorForbidden)
+func (e *RequestError) Is(target error) bool {
+	err, ok := errorKinds[e.Kind]
+	return ok && target == err
+}
+
+// Unwrap returns the error of the client the request failed with (e.g.: ErrorInvalidCredentials after a login)
+func (e *RequestError) Unwrap() error {
+	return e.cause
+}
+
+func newRequestError(resp *http.Response, body []byte) *RequestError {
+	e := &RequestError{
+		Status:     resp.Status,
+		StatusCode: resp.StatusCode,
+		Body:       body,
+	}
+
+	e.Message, e.Traceback = parseErrorBody(body)
+	e.Kind = classifyError(e.StatusCode, e.Message+"\n"+e.Traceback)
+	return e
+}
+
+// parseErrorBody extracts the message and the traceback from an HTML or JSON error body
+func parseErrorBody(body []byte) (message string, traceback string) {
+	s := strings.TrimSpace(string(body))
+
+	switch {
+	case strings.HasPrefix(s, "{"):
+		var data struct {
+			Return    interface{} `json:"return"`
+			Message   string      `json:"message"`
+			Traceback string      `json:"traceback"`
+		}
+
+		if err := json.Unmarshal(body, &data); err != nil {
+			return "", ""
+		}
+
+		message, traceback = data.Message, data.Traceback
+		if ret, ok := data.Return.(string); ok {
+			if isTraceback(ret) {
+				traceback = ret
+			} else if message == "" {
+				message = ret
+			}
+		}
+	case strings.HasPrefix(s, "<"):
+		if m := htmlMessagePattern.FindStringSubmatch(s); m != nil {
+			message = strings.Join(strings.Fields(html.UnescapeString(m[1])), " ")
+		}
+
+		if m := htmlTracebackPattern.FindStringSubmatch(s); m != nil {
+			traceback = strings.TrimSpace(html.UnescapeString(m[1]))
+		}
+	default:
+		message = s
+	}
+
+	// Last line of the traceback contains the exception and its message
+	if message == "" && traceback != "" {
+		lines := strings.Split(strings.TrimSpace(traceback), "\n")
+		message = strings.TrimSpace(lines[len(lines)-1])
+	}
+
+	return message, traceback
+}
+
+func isTraceback(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), "Traceback (most recent call last)")
+}
+
+// classifyError returns the kind of the status refined by exceptions of Salt found in the details
+func classifyError(status int, details string) ErrorKind {
+	switch status {
+	case http.StatusUnauthorized, http.StatusInternalServerError:
+		for _, e := range saltExceptions {
+			if strings.Contains(details, e.pattern) {
+				return e.kind
+			}
+		}
+	}
+
+	switch {
+	case status == http.StatusUnauthorized:
+		return ErrorKindUnauthorized
+	case status == http.StatusForbidden:
+		return ErrorKindForbidden
+	case status == http.StatusBadRequest, status == http.StatusNotFound, status == http.StatusNotAcceptable, status == http.StatusUnsupportedMediaType:
+		return ErrorKindBadRequest
+	case status == http.StatusBadGateway, status == http.StatusServiceUnavailable, status == http.StatusGatewayTimeout:
+		return ErrorKindUnavailable
+	case status >= 500:
+		return ErrorKindInternal
+	}
+
+	return ErrorKindUnknown
+}
@@ -0,0 +1,128 @@
+package cherrypy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestErrorForbidden(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "auth_login", "success")
+	tester.Setup(t, "run", "local_forbidden")
+
+	_, err := c.RunLocalCommand(context.Background(), Command{
+		Target:   ExpressionTarget{Expression: "minion1", Type: Glob},
+		Function: "cmd.run",
+	})
+
+	assert.True(t, errors.Is(err, ErrorForbidden))
+	assert.False(t, errors.Is(err, ErrorUnauthorized))
+
+	var rerr *RequestError
+	if assert.True(t, errors.As(err, &rerr)) {
+		assert.Equal(t, http.StatusUnauthorized, rerr.StatusCode)
+		assert.Equal(t, ErrorKindForbidden, rerr.Kind)
+		assert.Equal(t, "No permission -- see authorization schemes", rerr.Message)
+		assert.Contains(t, rerr.Traceback, `raise AuthorizationError("Authorization error occurred.")`)
+	}
+}
+
+func TestRequestErrorUnavailable(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "runner_unavailable")
+
+	_, err := c.RunRunnerCommand(context.Background(), Command{Function: "manage.up"})
+
+	assert.True(t, errors.Is(err, ErrorMasterUnavailable))
+	assert.Contains(t, err.Error(), "503 Service Unavailable: Salt request timed out.")
+}
+
+func TestRequestErrorInternal(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "hook", "failure")
+
+	err := c.Hook(context.Background(), "test", nil)
+
+	var rerr *RequestError
+	if assert.True(t, errors.As(err, &rerr)) {
+		assert.Equal(t, ErrorKindInternal, rerr.Kind)
+		assert.Equal(t, "An unexpected error occurred", rerr.Message)
+		assert.Empty(t, rerr.Traceback)
+	}
+	assert.True(t, errors.Is(err, ErrorInternal))
+}
+
+func TestParseErrorBody(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		message   string
+		traceback string
+	}{
+		{
+			name:      "json traceback",
+			body:      `{"status": 500, "return": "Traceback (most recent call last):\n  File \"app.py\", line 1\nsalt.exceptions.SaltInvocationError: Missing required argument mods\n"}`,
+			message:   "salt.exceptions.SaltInvocationError: Missing required argument mods",
+			traceback: "Traceback (most recent call last):\n  File \"app.py\", line 1\nsalt.exceptions.SaltInvocationError: Missing required argument mods\n",
+		},
+		{
+			name:      "json message",
+			body:      `{"status": 403, "message": "Forbidden", "traceback": "Traceback"}`,
+			message:   "Forbidden",
+			traceback: "Traceback",
+		},
+		{
+			name:    "html",
+			body:    "<html><body><h2>400 Bad Request</h2>\n<p>Missing\n  &#39;tgt&#39;</p>\n<pre id=\"traceback\"></pre></body></html>",
+			message: "Missing 'tgt'",
+		},
+		{
+			name:    "text",
+			body:    " Bad Gateway\n",
+			message: "Bad Gateway",
+		},
+		{
+			name: "invalid json",
+			body: `{"status": `,
+		},
+	}
+
+	for _, tc := range cases {
+		message, traceback := parseErrorBody([]byte(tc.body))
+
+		assert.Equal(t, tc.message, message, tc.name)
+		assert.Equal(t, tc.traceback, traceback, tc.name)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		status  int
+		details string
+		kind    ErrorKind
+	}{
+		{http.StatusUnauthorized, "", ErrorKindUnauthorized},
+		{http.StatusUnauthorized, "salt.exceptions.EauthAuthenticationError", ErrorKindUnauthorized},
+		{http.StatusUnauthorized, "salt.exceptions.AuthorizationError: Authorization error occurred.", ErrorKindForbidden},
+		{http.StatusForbidden, "Bad IP", ErrorKindForbidden},
+		{http.StatusBadRequest, "", ErrorKindBadRequest},
+		{http.StatusInternalServerError, "salt.exceptions.SaltInvocationError: Missing required argument", ErrorKindBadRequest},
+		{http.StatusInternalServerError, "'foo.bar' is not available.", ErrorKindBadRequest},
+		{http.StatusInternalServerError, "salt.exceptions.SaltReqTimeoutError: Message timed out", ErrorKindUnavailable},
+		{http.StatusInternalServerError, "KeyError: 'return'", ErrorKindInternal},
+		{http.StatusServiceUnavailable, "", ErrorKindUnavailable},
+		{http.StatusGatewayTimeout, "", ErrorKindUnavailable},
+		{http.StatusConflict, "", ErrorKindUnknown},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.kind, classifyError(tc.status, tc.details), "%d %s", tc.status, tc.details)
+	}
+}
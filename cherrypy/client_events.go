@@ -0,0 +1,277 @@
+package cherrypy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultEventRetryDelay is used between reconnection attempts unless the server advises otherwise
+const defaultEventRetryDelay = time.Second
+
+const eventTimeLayout = "2006-01-02T15:04:05.999999"
+
+// Event is a message received from Salt's event bus
+type Event struct {
+	Tag       string
+	Data      map[string]interface{}
+	Timestamp time.Time
+}
+
+type eventMessage struct {
+	Tag  string                 `json:"tag"`
+	Data map[string]interface{} `json:"data"`
+}
+
+type eventStream struct {
+	client    *Client
+	saltToken string
+	lastID    string
+	retry     time.Duration
+}
+
+/*
+Events subscribes to Salt's event bus using the token retrieved by Login()
+
+Events are delivered on the returned channel until the context is cancelled.
+If the connection drops, the stream is re-established with the Last-Event-ID header.
+The channel is closed when the subscription ends.
+
+https://docs.saltstack.com/en/latest/ref/netapi/all/salt.netapi.rest_cherrypy.html#events
+*/
+func (c *Client) Events(ctx context.Context) (<-chan Event, error) {
+	if c.Token() == "" {
+		return nil, ErrorNotAuthenticated
+	}
+
+	return c.subscribeEvents(ctx, "")
+}
+
+/*
+EventsWithSaltToken subscribes to Salt's event bus using a token issued by Salt's eauth system
+instead of the rest_cherrypy session (e.g.: a token created with salt -T)
+
+See Events() for details.
+
+https://docs.saltstack.com/en/latest/ref/netapi/all/salt.netapi.rest_cherrypy.html#events
+*/
+func (c *Client) EventsWithSaltToken(ctx context.Context, saltToken string) (<-chan Event, error) {
+	return c.subscribeEvents(ctx, saltToken)
+}
+
+func (c *Client) subscribeEvents(ctx context.Context, saltToken string) (<-chan Event, error) {
+	s := &eventStream{
+		client:    c,
+		saltToken: saltToken,
+		retry:     defaultEventRetryDelay,
+	}
+
+	body, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan Event)
+	go s.run(ctx, body, ch)
+
+	return ch, nil
+}
+
+func (s *eventStream) connect(ctx context.Context) (io.ReadCloser, error) {
+	c := s.client
+	retried := false
+
+	for {
+		if s.saltToken == "" && c.shouldRenewToken() {
+			if err := c.renewToken(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		// Session token is sent in X-Auth-Token header by newRequest()
+		req, err := c.newRequest(ctx, "GET", "events", nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if s.saltToken != "" {
+			req.URL.RawQuery = url.Values{"salt_token": {s.saltToken}}.Encode()
+		}
+
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("Cache-Control", "no-cache")
+		if s.lastID != "" {
+			req.Header.Set("Last-Event-ID", s.lastID)
+		}
+
+		c.debug("Sending event stream request", Field{"last_event_id", s.lastID})
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, redactURLError(err)
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			return resp.Body, nil
+		}
+
+		// Not checking for error as it does not matter
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusUnauthorized && s.saltToken == "" && !retried {
+			if err := c.relogin(ctx, req.Header.Get("X-Auth-Token"), req.URL.Path); err != nil {
+				return nil, err
+			}
+
+			retried = true
+			continue
+		}
+
+		return nil, newRequestError(resp, body)
+	}
+}
+
+// redactURLError hides the Salt token in the URL of errors returned by the HTTP client
+func redactURLError(err error) error {
+	uerr, ok := err.(*url.Error)
+	if !ok {
+		return err
+	}
+
+	u, perr := url.Parse(uerr.URL)
+	if perr != nil {
+		return &url.Error{Op: uerr.Op, URL: redacted, Err: uerr.Err}
+	}
+
+	q := u.Query()
+	if q.Get("salt_token") == "" {
+		return err
+	}
+
+	q.Set("salt_token", redacted)
+	u.RawQuery = q.Encode()
+	return &url.Error{Op: uerr.Op, URL: u.String(), Err: uerr.Err}
+}
+
+func (s *eventStream) run(ctx context.Context, body io.ReadCloser, ch chan<- Event) {
+	defer close(ch)
+
+	for {
+		err := s.read(ctx, body, ch)
+		body.Close()
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.client.warn("Event stream disconnected", Field{"error", err})
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(s.retry):
+			}
+
+			body, err = s.connect(ctx)
+			if err == nil {
+				break
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			s.client.warn("Reconnecting to event stream failed", Field{"error", err})
+		}
+	}
+}
+
+// read parses Server-Sent Events until the stream ends
+// https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+func (s *eventStream) read(ctx context.Context, r io.Reader, ch chan<- Event) error {
+	br := bufio.NewReader(r)
+
+	var tag string
+	var data []string
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if len(data) > 0 {
+				ev, err := parseEvent(tag, strings.Join(data, "\n"))
+				if err != nil {
+					s.client.warn("Discarding malformed event", Field{"tag", tag}, Field{"error", err})
+				} else {
+					select {
+					case ch <- *ev:
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+			}
+
+			tag = ""
+			data = nil
+			continue
+		}
+
+		// Lines starting with colon are comments
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field = line[:i]
+			value = strings.TrimPrefix(line[i+1:], " ")
+		}
+
+		switch field {
+		case "data":
+			data = append(data, value)
+		case "id":
+			s.lastID = value
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil {
+				s.retry = time.Duration(ms) * time.Millisecond
+			}
+		case "tag":
+			// Salt sends the tag as a separate field before data
+			tag = value
+		}
+	}
+}
+
+func parseEvent(tag string, data string) (*Event, error) {
+	var msg eventMessage
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		return nil, err
+	}
+
+	ev := Event{
+		Tag:  msg.Tag,
+		Data: msg.Data,
+	}
+
+	if ev.Tag == "" {
+		ev.Tag = tag
+	}
+
+	if stamp, ok := msg.Data["_stamp"].(string); ok {
+		if t, err := time.Parse(eventTimeLayout, stamp); err == nil {
+			ev.Timestamp = t
+		}
+	}
+
+	return &ev, nil
+}
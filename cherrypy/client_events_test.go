@@ -0,0 +1,206 @@
+package cherrypy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testEventJobNew = `{"tag": "salt/job/20200202224725441938/new", "data": {"jid": "20200202224725441938", "minions": ["minion1"], "_stamp": "2020-02-02T22:47:25.490631"}}`
+
+func writeEvent(w http.ResponseWriter, id string, tag string, data string) {
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "tag: %s\ndata: %s\n\n", tag, data)
+	w.(http.Flusher).Flush()
+}
+
+func receiveEvent(t *testing.T, ch <-chan Event) Event {
+	select {
+	case ev, ok := <-ch:
+		if !ok {
+			t.Fatal("event stream was closed")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	return Event{}
+}
+
+func TestEvents(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+
+	tester.Do("/events", func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, testToken, req.Header.Get("X-Auth-Token"))
+		assert.Empty(t, req.URL.RawQuery)
+		assert.Equal(t, "text/event-stream", req.Header.Get("Accept"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "retry: 400\n\n")
+		writeEvent(w, "", "salt/job/20200202224725441938/new", testEventJobNew)
+		<-req.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := c.Events(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ev := receiveEvent(t, ch)
+	assert.Equal(t, "salt/job/20200202224725441938/new", ev.Tag)
+	assert.Equal(t, "20200202224725441938", ev.Data["jid"])
+	assert.Equal(t, time.Date(2020, time.February, 2, 22, 47, 25, 490631000, time.UTC), ev.Timestamp)
+
+	cancel()
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func TestEventsReconnect(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+
+	calls := 0
+	tester.Do("/events", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "retry: 10\n\n")
+		if calls == 1 {
+			assert.Empty(t, req.Header.Get("Last-Event-ID"))
+			writeEvent(w, "1", "salt/event/first", `{"tag": "salt/event/first", "data": {}}`)
+			return
+		}
+
+		assert.Equal(t, "1", req.Header.Get("Last-Event-ID"))
+		writeEvent(w, "2", "salt/event/second", `{"tag": "salt/event/second", "data": {}}`)
+		<-req.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := c.Events(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "salt/event/first", receiveEvent(t, ch).Tag)
+	assert.Equal(t, "salt/event/second", receiveEvent(t, ch).Tag)
+}
+
+func TestEventsWithSaltToken(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+
+	tester.Do("/events", func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("salt_token") != "salt-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeEvent(w, "", "salt/job/20200202224725441938/new", testEventJobNew)
+		<-req.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := c.EventsWithSaltToken(ctx, "invalid")
+	assert.Error(t, err)
+
+	ch, err := c.EventsWithSaltToken(ctx, "salt-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "salt/job/20200202224725441938/new", receiveEvent(t, ch).Tag)
+}
+
+// syncBuffer is written by the event stream goroutine while the test reads it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func TestEventsReconnectFailureHidesToken(t *testing.T) {
+	tester, _ := setup(t)
+	defer tester.Close()
+
+	var buf syncBuffer
+	c, err := NewClientWithOptions(tester.URL,
+		WithCredentials(testUsername, testPassword, testEAuth),
+		WithLogger(NewStdLogger(log.New(&buf, "", 0), LogLevelWarn)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetToken(testToken)
+
+	served := make(chan struct{})
+	tester.Do("/events", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "retry: 10\n\n")
+		writeEvent(w, "", "salt/event/first", `{"tag": "salt/event/first", "data": {}}`)
+		close(served)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := c.Events(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-served
+	receiveEvent(t, ch)
+	tester.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(buf.String(), "Reconnecting to event stream failed") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Contains(t, buf.String(), "Reconnecting to event stream failed")
+	assert.NotContains(t, buf.String(), testToken)
+}
+
+func TestRedactURLError(t *testing.T) {
+	err := redactURLError(&url.Error{Op: "Get", URL: "http://master:8000/events?salt_token=secret", Err: errors.New("connection refused")})
+
+	assert.NotContains(t, err.Error(), "secret")
+	assert.Contains(t, err.Error(), "connection refused")
+
+	plain := errors.New("failed")
+	assert.Equal(t, plain, redactURLError(plain))
+}
@@ -3,7 +3,6 @@ package cherrypy
 import (
 	"context"
 	"fmt"
-	"log"
 )
 
 type hookResponse struct {
@@ -26,7 +25,7 @@ func (c *Client) Hook(ctx context.Context, id string, data interface{}) error {
 		return err
 	}
 
-	log.Println("[DEBUG] Sending authentication request")
+	c.debug("Sending hook request", Field{"id", id})
 	var resp hookResponse
 	_, err = c.do(req, &resp)
 	if err != nil {
@@ -0,0 +1,86 @@
+package cherrypy
+
+import (
+	"context"
+)
+
+// Authenticator manages the eauth session with rest_cherrypy
+type Authenticator interface {
+	Login(ctx context.Context) error
+	Logout(ctx context.Context) error
+	Session() *Session
+	Token() string
+	SetToken(token string)
+}
+
+// MinionService retrieves minions and their grains
+type MinionService interface {
+	Minion(ctx context.Context, id string) (*Minion, error)
+	Minions(ctx context.Context) ([]Minion, error)
+	PreviewTarget(ctx context.Context, t Target) ([]string, error)
+}
+
+// JobService submits jobs to minions and queries the job cache
+type JobService interface {
+	SubmitJob(ctx context.Context, job MinionJob) (*AsyncMinionJobResult, error)
+	SubmitJobs(ctx context.Context, jobs []MinionJob) ([]AsyncMinionJobResult, error)
+	Job(ctx context.Context, id string) (*JobDetails, error)
+	Jobs(ctx context.Context) ([]Job, error)
+	QueryJobs(ctx context.Context, q JobQuery) ([]Job, error)
+	WaitForJob(ctx context.Context, id string, opts WaitOptions) (*JobWaitResult, error)
+}
+
+// KeyService manages minion keys
+type KeyService interface {
+	Keys(ctx context.Context) (*KeyResult, error)
+	Key(ctx context.Context, id string) (string, error)
+	GenerateKeyPair(ctx context.Context, id string, keySize int, force bool) (*MinionKeyPair, error)
+	AcceptKeys(ctx context.Context, match string, opts KeyActionOptions) ([]KeyChange, error)
+	AcceptKeyDict(ctx context.Context, keys KeyResult, opts KeyActionOptions) ([]KeyChange, error)
+	RejectKeys(ctx context.Context, match string, opts KeyActionOptions) ([]KeyChange, error)
+	DeleteKeys(ctx context.Context, match string) ([]KeyChange, error)
+	KeyFingerprints(ctx context.Context, match string, hashType string) (*KeyFingerprints, error)
+}
+
+// CommandRunner runs commands with the local, runner, wheel and ssh clients
+type CommandRunner interface {
+	RunCommand(ctx context.Context, cmd Command) (interface{}, error)
+	RunCommands(ctx context.Context, cmds []Command) ([]interface{}, error)
+	RunLocalCommand(ctx context.Context, cmd Command) (LocalResult, error)
+	RunRunnerCommand(ctx context.Context, cmd Command) (*MasterResult, error)
+	RunWheelCommand(ctx context.Context, cmd Command) (*MasterResult, error)
+	RunLocalAsyncCommand(ctx context.Context, cmd Command) (*AsyncMinionJobResult, error)
+	RunRunnerAsyncCommand(ctx context.Context, cmd Command) (*AsyncMasterJobResult, error)
+	RunWheelAsyncCommand(ctx context.Context, cmd Command) (*AsyncMasterJobResult, error)
+	RunBatch(ctx context.Context, cmd Command, opts BatchOptions, fn func(LocalResult) error) error
+	RunSSHCommand(ctx context.Context, cmd Command) (SSHResult, error)
+}
+
+// EventService fires events and subscribes to Salt's event bus
+type EventService interface {
+	Hook(ctx context.Context, id string, data interface{}) error
+	Events(ctx context.Context) (<-chan Event, error)
+	EventsWithSaltToken(ctx context.Context, saltToken string) (<-chan Event, error)
+	WebSocketEvents(ctx context.Context, opts WebSocketOptions) (<-chan Event, error)
+}
+
+/*
+API is implemented by Client; consumers can depend on it or on one of the smaller
+interfaces it is composed of to replace the client in tests (e.g.: with package cherrypymock)
+
+Example usage:
+	type Deployer struct {
+		Salt cherrypy.CommandRunner
+	}
+*/
+type API interface {
+	Authenticator
+	MinionService
+	JobService
+	KeyService
+	CommandRunner
+	EventService
+	Stats(ctx context.Context) (map[string]interface{}, error)
+}
+
+var _ API = (*Client)(nil)
@@ -3,7 +3,7 @@ package cherrypy
 import (
 	"context"
 	"errors"
-	"log"
+	"sort"
 	"time"
 )
 
@@ -28,12 +28,15 @@ type JobDetails struct {
 	Job
 	Minions []string
 	Returns map[string]interface{}
+
+	// Results contain return, retcode and success per minion which returned
+	Results map[string]MinionReturn
 }
 
 type jobResult struct {
 	Return     interface{} `json:"return"`
 	ReturnCode int         `json:"retcode"`
-	Success    bool        `json:"success"`
+	Success    *bool       `json:"success"`
 }
 
 type jobInfo struct {
@@ -46,6 +49,7 @@ type jobInfo struct {
 	StartTime  saltTime             `json:"StartTime"`
 	Minions    []string             `json:"Minions"`
 	Arguments  []interface{}        `json:"Arguments"`
+	Error      string               `json:"Error"`
 }
 
 type jobDetailResponse struct {
@@ -70,19 +74,29 @@ func (c *Client) Job(ctx context.Context, id string) (*JobDetails, error) {
 		return nil, err
 	}
 
-	log.Println("[DEBUG] Sending job details request")
+	c.debug("Sending job details request", Field{"jid", id})
 	var resp jobDetailResponse
 	_, err = c.do(req, &resp)
 	if err != nil {
 		return nil, err
 	}
 
+	if len(resp.Info) == 0 || resp.Info[0].Error != "" {
+		return nil, ErrorJobNotFound
+	}
+
 	j := resp.Info[0]
 	job := JobDetails{
 		Minions: j.Minions,
-		Returns: resp.Returns[0],
+		Returns: map[string]interface{}{},
+	}
+
+	if len(resp.Returns) > 0 && resp.Returns[0] != nil {
+		job.Returns = resp.Returns[0]
 	}
 
+	job.Results = parseJobResults(j.ID, j.Result, job.Returns)
+
 	job.ID = j.ID
 	job.Function = j.Function
 	job.StartTime = j.StartTime.Time
@@ -104,7 +118,7 @@ func (c *Client) Jobs(ctx context.Context) ([]Job, error) {
 		return nil, err
 	}
 
-	log.Println("[DEBUG] Sending job list request")
+	c.debug("Sending job list request")
 	var resp jobListResponse
 	_, err = c.do(req, &resp)
 	if err != nil {
@@ -114,25 +128,76 @@ func (c *Client) Jobs(ctx context.Context) ([]Job, error) {
 	jobs := make([]Job, len(resp.Jobs[0]))
 	i := 0
 	for k, v := range resp.Jobs[0] {
-		args, kwArgs := parseArgs(v.Arguments)
-		target := parseTarget(v)
-
-		jobs[i] = Job{
-			ID:          k,
-			Function:    v.Function,
-			StartTime:   v.StartTime.Time,
-			User:        v.User,
-			Target:      target,
-			Arguments:   args,
-			KWArguments: kwArgs,
-		}
-
+		jobs[i] = newJob(k, v)
 		i++
 	}
 
 	return jobs, nil
 }
 
+func newJob(id string, info jobInfo) Job {
+	args, kwArgs := parseArgs(info.Arguments)
+
+	return Job{
+		ID:          id,
+		Function:    info.Function,
+		StartTime:   info.StartTime.Time,
+		User:        info.User,
+		Target:      parseTarget(info),
+		Arguments:   args,
+		KWArguments: kwArgs,
+	}
+}
+
+// Succeeded returns minions which returned successfully in sorted order
+func (j *JobDetails) Succeeded() []string {
+	return j.filterResults(true)
+}
+
+// Failed returns minions which returned with a non-zero retcode or an unsuccessful result in sorted order
+func (j *JobDetails) Failed() []string {
+	return j.filterResults(false)
+}
+
+// WithOutcome returns minions which returned with the outcome in sorted order
+func (j *JobDetails) WithOutcome(outcome Outcome) []string {
+	return minionsWithOutcome(j.Results, outcome)
+}
+
+// Unreachable returns minions reported as not returning or not connected in sorted order
+func (j *JobDetails) Unreachable() []string {
+	return minionsWithOutcome(j.Results, OutcomeNoResponse, OutcomeNotConnected)
+}
+
+func (j *JobDetails) filterResults(success bool) []string {
+	minions := []string{}
+	for id, r := range j.Results {
+		if r.Success == success {
+			minions = append(minions, id)
+		}
+	}
+
+	sort.Strings(minions)
+	return minions
+}
+
+/*
+parseJobResults combines results of the job info with returns;
+returns of minions missing from the info (e.g.: older masters) are considered successful
+*/
+func parseJobResults(jid string, results map[string]jobResult, returns map[string]interface{}) map[string]MinionReturn {
+	res := make(map[string]MinionReturn, len(returns))
+	for id, v := range returns {
+		res[id] = newMinionReturn(jid, v, 0, true)
+	}
+
+	for id, r := range results {
+		res[id] = newMinionReturn(jid, r.Return, r.ReturnCode, r.ReturnCode == 0 && (r.Success == nil || *r.Success))
+	}
+
+	return res
+}
+
 func parseTarget(j jobInfo) Target {
 	targetType := targetTypes[j.TargetType]
 	switch targetType {
@@ -0,0 +1,125 @@
+package cherrypy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// jobQueryTimeLayout is parsed by the jobs.list_jobs runner on the master
+const jobQueryTimeLayout = "2006-01-02T15:04:05"
+
+// JobOrder sets the order of jobs returned by QueryJobs()
+type JobOrder int
+
+const (
+	// OldestFirst orders jobs by ascending start time
+	OldestFirst JobOrder = iota
+	// NewestFirst orders jobs by descending start time
+	NewestFirst
+)
+
+/*
+JobQuery filters jobs returned by QueryJobs()
+
+Function, Target, StartTime and EndTime are sent to the master; other fields are applied by the client.
+*/
+type JobQuery struct {
+	// Function matches the function of the job; supports glob patterns (e.g.: state.*)
+	Function string
+
+	// Target matches the target of the job (e.g.: web*)
+	Target string
+
+	// User matches the user who started the job
+	User string
+
+	// StartTime excludes jobs started before the time
+	StartTime time.Time
+
+	// EndTime excludes jobs started after the time
+	EndTime time.Time
+
+	// Limit returns only the first jobs in the order; all jobs are returned if not set
+	Limit int
+
+	Order JobOrder
+}
+
+/*
+QueryJobs retrieves jobs matching the query using the jobs.list_jobs runner
+
+Filtering by function, target and start time happens on the master
+instead of retrieving the whole job cache like Jobs().
+
+https://docs.saltstack.com/en/latest/ref/runners/all/salt.runners.jobs.html#salt.runners.jobs.list_jobs
+*/
+func (c *Client) QueryJobs(ctx context.Context, q JobQuery) ([]Job, error) {
+	res, err := c.RunRunnerCommand(ctx, Command{
+		Function:    "jobs.list_jobs",
+		KWArguments: q.kwargs(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !res.Success {
+		return nil, fmt.Errorf("jobs.list_jobs failed: %v", res.Return)
+	}
+
+	var infos map[string]jobInfo
+	if err := res.Decode(&infos); err != nil {
+		return nil, err
+	}
+
+	jobs := make([]Job, 0, len(infos))
+	for id, info := range infos {
+		if q.User != "" && info.User != q.User {
+			continue
+		}
+
+		jobs = append(jobs, newJob(id, info))
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		a, b := jobs[i], jobs[j]
+		if q.Order == NewestFirst {
+			a, b = b, a
+		}
+
+		if !a.StartTime.Equal(b.StartTime) {
+			return a.StartTime.Before(b.StartTime)
+		}
+
+		return a.ID < b.ID
+	})
+
+	if q.Limit > 0 && len(jobs) > q.Limit {
+		jobs = jobs[:q.Limit]
+	}
+
+	return jobs, nil
+}
+
+func (q JobQuery) kwargs() map[string]interface{} {
+	kwargs := make(map[string]interface{})
+	if q.Function != "" {
+		kwargs["search_function"] = q.Function
+	}
+
+	if q.Target != "" {
+		kwargs["search_target"] = q.Target
+	}
+
+	// StartTime of jobs is parsed as UTC so the same zone is used for the query
+	if !q.StartTime.IsZero() {
+		kwargs["start_time"] = q.StartTime.UTC().Format(jobQueryTimeLayout)
+	}
+
+	if !q.EndTime.IsZero() {
+		kwargs["end_time"] = q.EndTime.UTC().Format(jobQueryTimeLayout)
+	}
+
+	return kwargs
+}
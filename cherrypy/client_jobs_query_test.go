@@ -0,0 +1,62 @@
+package cherrypy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testJobQuery() JobQuery {
+	return JobQuery{
+		Function:  "cmd.*",
+		Target:    "*",
+		StartTime: time.Date(2020, time.February, 2, 20, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2020, time.February, 2, 22, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestQueryJobs(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "runner_list_jobs")
+
+	res, err := c.QueryJobs(context.Background(), testJobQuery())
+
+	assert.NoError(t, err)
+	assert.Len(t, res, 3)
+	assert.Equal(t, "20200202204012116573", res[0].ID)
+	assert.Equal(t, "cmd.script", res[0].Function)
+	assert.Equal(t, "root", res[0].User)
+	assert.Equal(t, "*", res[0].Target.(*ExpressionTarget).Expression)
+	assert.Equal(t, []interface{}{"salt://scripts/cleanup.sh"}, res[0].Arguments)
+	assert.Equal(t, "20200202205404546719", res[1].ID)
+	assert.Equal(t, testSampleJobID, res[2].ID)
+}
+
+func TestQueryJobsUserLimitOrder(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "runner_list_jobs")
+
+	q := testJobQuery()
+	q.User = "sudo_vagrant"
+	q.Order = NewestFirst
+	q.Limit = 1
+
+	res, err := c.QueryJobs(context.Background(), q)
+
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+	assert.Equal(t, testSampleJobID, res[0].ID)
+	assert.Equal(t, time.Date(2020, time.February, 2, 21, 2, 31, 414902000, time.UTC), res[0].StartTime)
+}
+
+func TestJobQueryKWArgs(t *testing.T) {
+	assert.Empty(t, JobQuery{User: "root", Limit: 10}.kwargs())
+
+	local := time.FixedZone("UTC+3", 3*60*60)
+	kwargs := JobQuery{StartTime: time.Date(2020, time.February, 2, 23, 0, 0, 0, local)}.kwargs()
+	assert.Equal(t, map[string]interface{}{"start_time": "2020-02-02T20:00:00"}, kwargs)
+}
@@ -38,7 +38,7 @@ func TestGetMissingJob(t *testing.T) {
 
 	_, err := c.Job(context.Background(), "SampleMissingJobId")
 
-	assert.Error(t, err)
+	assert.Equal(t, ErrorJobNotFound, err)
 }
 
 func TestGetJobs(t *testing.T) {
@@ -72,3 +72,25 @@ func TestGetJobs(t *testing.T) {
 	assert.Equal(t, 1, len(job.Arguments))
 	assert.Equal(t, "echo Hello", job.Arguments[0])
 }
+
+func TestGetJobResults(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "jobs_get", "completed")
+
+	res, err := c.Job(context.Background(), testSampleJobID)
+
+	assert.NoError(t, err)
+	assert.Len(t, res.Results, 2)
+	assert.Equal(t, MinionReturn{JID: testSampleJobID, Return: "Hello", ReturnCode: 0, Success: true, Outcome: OutcomeSuccess}, res.Results["minion1"])
+	assert.Equal(t, 127, res.Results["minion2"].ReturnCode)
+	assert.False(t, res.Results["minion2"].Success)
+	assert.Equal(t, []string{"minion1"}, res.Succeeded())
+	assert.Equal(t, []string{"minion2"}, res.Failed())
+}
+
+func TestParseJobResultsWithoutInfo(t *testing.T) {
+	res := parseJobResults(testSampleJobID, nil, map[string]interface{}{"minion1": true})
+
+	assert.Equal(t, MinionReturn{JID: testSampleJobID, Return: true, Success: true, Outcome: OutcomeSuccess}, res["minion1"])
+}
@@ -0,0 +1,156 @@
+package cherrypy
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// defaultWaitInterval is used between polls unless WaitOptions specify otherwise
+const defaultWaitInterval = time.Second
+
+// WaitOptions control how WaitForJob() polls the job
+type WaitOptions struct {
+	// Interval between polls; 1 second if not set
+	Interval time.Duration
+
+	// Multiplier increases the interval after each poll (e.g.: 2 doubles it); interval is fixed if not greater than 1
+	Multiplier float64
+
+	// MaxInterval limits the interval increased by Multiplier
+	MaxInterval time.Duration
+
+	// Timeout limits the total time to wait; only the context is used if not set
+	Timeout time.Duration
+
+	// Minions to wait for (e.g.: Minions of AsyncMinionJobResult); minions of the job are used if not set
+	Minions []string
+}
+
+// JobWaitResult contains state of a job when WaitForJob() stopped polling
+type JobWaitResult struct {
+	// Job is the last details retrieved
+	Job *JobDetails
+
+	// Returned contains minions which returned including the failed ones
+	Returned []string
+
+	// Failed contains minions which returned with a non-zero retcode or an unsuccessful result
+	Failed []string
+
+	// Missing contains minions which did not return
+	Missing []string
+
+	// Expected contains minions to wait for; empty while the job cache has not recorded the targeted minions
+	// and for jobs which do not target minions (e.g.: runner jobs)
+	Expected []string
+
+	// Unreachable contains minions reported by Salt as not returning or not connected;
+	// they are included in Returned and Failed as the job will not receive their returns
+	Unreachable []string
+}
+
+/*
+Complete reports whether all minions returned
+
+If the minions to wait for are not known, the job is complete once any return was received;
+jobs of runners are recorded without minions and with a single return of the master (e.g.: master_master).
+*/
+func (r *JobWaitResult) Complete() bool {
+	if len(r.Expected) == 0 {
+		return len(r.Returned) > 0
+	}
+
+	return len(r.Missing) == 0
+}
+
+/*
+WaitForJob polls the job with Job() until all targeted minions return
+
+Minions of the job might not be recorded right after it is published; polling continues until they are known
+or a return is received. Set Minions of the options (e.g.: to Minions of AsyncMinionJobResult)
+to wait for all of them if the master does not record them. Jobs of runners (e.g.: the jid of
+AsyncMasterJobResult) are complete once the master returns.
+
+If the context is cancelled or the timeout elapses first, the result of the last poll
+is returned together with the error of the context (e.g.: context.DeadlineExceeded).
+Result is nil only if no poll succeeded.
+
+If the job was not found; ErrorJobNotFound will be returned.
+*/
+func (c *Client) WaitForJob(ctx context.Context, id string, opts WaitOptions) (*JobWaitResult, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultWaitInterval
+	}
+
+	var res *JobWaitResult
+	for {
+		job, err := c.Job(ctx, id)
+		if err != nil {
+			if ctx.Err() != nil {
+				return res, ctx.Err()
+			}
+
+			return res, err
+		}
+
+		res = newJobWaitResult(job, opts.Minions)
+		if res.Complete() {
+			return res, nil
+		}
+
+		c.debug("Waiting for minions to return", Field{"jid", id}, Field{"missing", len(res.Missing)}, Field{"delay", interval})
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res, ctx.Err()
+		case <-timer.C:
+		}
+
+		if opts.Multiplier > 1 {
+			interval = time.Duration(float64(interval) * opts.Multiplier)
+			if opts.MaxInterval > 0 && interval > opts.MaxInterval {
+				interval = opts.MaxInterval
+			}
+		}
+	}
+}
+
+func newJobWaitResult(job *JobDetails, minions []string) *JobWaitResult {
+	if len(minions) == 0 {
+		minions = job.Minions
+	}
+
+	res := JobWaitResult{
+		Job:         job,
+		Expected:    append([]string{}, minions...),
+		Returned:    []string{},
+		Failed:      job.Failed(),
+		Missing:     []string{},
+		Unreachable: job.Unreachable(),
+	}
+
+	for id := range job.Results {
+		res.Returned = append(res.Returned, id)
+	}
+
+	for _, id := range minions {
+		if _, ok := job.Results[id]; !ok {
+			res.Missing = append(res.Missing, id)
+		}
+	}
+
+	sort.Strings(res.Expected)
+	sort.Strings(res.Returned)
+	sort.Strings(res.Missing)
+
+	return &res
+}
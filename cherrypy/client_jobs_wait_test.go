@@ -0,0 +1,136 @@
+package cherrypy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForJob(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	calls := setupSequence(t, tester, "jobs_get", "success", "success", "completed")
+
+	res, err := c.WaitForJob(context.Background(), testSampleJobID, WaitOptions{
+		Interval:    time.Millisecond,
+		Multiplier:  2,
+		MaxInterval: 3 * time.Millisecond,
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, res.Complete())
+	assert.Equal(t, 3, *calls)
+	assert.Equal(t, []string{"minion1", "minion2"}, res.Returned)
+	assert.Equal(t, []string{"minion2"}, res.Failed)
+	assert.Empty(t, res.Missing)
+	assert.Equal(t, testSampleJobID, res.Job.ID)
+}
+
+func TestWaitForJobNotYetRecorded(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	calls := setupSequence(t, tester, "jobs_get", "published", "success", "completed")
+
+	res, err := c.WaitForJob(context.Background(), testSampleJobID, WaitOptions{
+		Interval: time.Millisecond,
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, res.Complete())
+	assert.Equal(t, 3, *calls)
+	assert.Equal(t, []string{"minion1", "minion2"}, res.Expected)
+}
+
+func TestWaitForJobNeverRecorded(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	setupSequence(t, tester, "jobs_get", "published")
+
+	res, err := c.WaitForJob(context.Background(), testSampleJobID, WaitOptions{
+		Interval: 5 * time.Millisecond,
+		Timeout:  50 * time.Millisecond,
+	})
+
+	assert.Equal(t, context.DeadlineExceeded, err)
+	if assert.NotNil(t, res) {
+		assert.False(t, res.Complete())
+		assert.Empty(t, res.Expected)
+		assert.Empty(t, res.Returned)
+	}
+}
+
+func TestWaitForRunnerJob(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	calls := setupSequence(t, tester, "jobs_get", "runner_published", "runner_completed")
+
+	res, err := c.WaitForJob(context.Background(), "20200205202513331428", WaitOptions{
+		Interval: time.Millisecond,
+		Timeout:  time.Second,
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, res.Complete())
+	assert.Equal(t, 2, *calls)
+	assert.Empty(t, res.Expected)
+	assert.Equal(t, []string{"master_master"}, res.Returned)
+	assert.Empty(t, res.Failed)
+}
+
+func TestWaitForJobTimeout(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	setupSequence(t, tester, "jobs_get", "success")
+
+	res, err := c.WaitForJob(context.Background(), testSampleJobID, WaitOptions{
+		Interval: 5 * time.Millisecond,
+		Timeout:  50 * time.Millisecond,
+	})
+
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.False(t, res.Complete())
+	assert.Equal(t, []string{"minion1"}, res.Returned)
+	assert.Empty(t, res.Failed)
+	assert.Equal(t, []string{"minion2"}, res.Missing)
+}
+
+func TestWaitForJobCancelled(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	setupSequence(t, tester, "jobs_get", "success")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	res, err := c.WaitForJob(ctx, testSampleJobID, WaitOptions{})
+
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, res)
+}
+
+func TestWaitForJobMinions(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	calls := setupSequence(t, tester, "jobs_get", "success")
+
+	res, err := c.WaitForJob(context.Background(), testSampleJobID, WaitOptions{
+		Minions: []string{"minion1"},
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, res.Complete())
+	assert.Equal(t, 1, *calls)
+}
+
+func TestWaitForMissingJob(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "jobs_get", "missing")
+
+	res, err := c.WaitForJob(context.Background(), "SampleMissingJobId", WaitOptions{})
+
+	assert.Equal(t, ErrorJobNotFound, err)
+	assert.Nil(t, res)
+}
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 )
 
 var (
@@ -72,7 +71,7 @@ func (c *Client) Keys(ctx context.Context) (*KeyResult, error) {
 		return nil, err
 	}
 
-	log.Println("[DEBUG] Sending key list request")
+	c.debug("Sending key list request")
 	var resp keyListResponse
 	_, err = c.do(req, &resp)
 	if err != nil {
@@ -95,7 +94,7 @@ func (c *Client) Key(ctx context.Context, id string) (string, error) {
 		return "", err
 	}
 
-	log.Println("[DEBUG] Sending key details request")
+	c.debug("Sending key details request", Field{"minion", id})
 	var resp keyDetailsResponse
 	_, err = c.do(req, &resp)
 	if err != nil {
@@ -134,7 +133,7 @@ func (c *Client) GenerateKeyPair(ctx context.Context, id string, keySize int, fo
 		return nil, err
 	}
 
-	log.Println("[DEBUG] Sending generate key request")
+	c.debug("Sending generate key request", Field{"minion", id})
 	br := new(bytes.Buffer)
 	_, err = c.do(req, br)
 	if err != nil {
@@ -0,0 +1,243 @@
+package cherrypy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// KeyState indicates where a minion key is stored on the master
+type KeyState string
+
+const (
+	// KeyStateAccepted is the state of keys accepted by the master
+	KeyStateAccepted KeyState = "minions"
+	// KeyStatePending is the state of keys waiting to be accepted
+	KeyStatePending KeyState = "minions_pre"
+	// KeyStateRejected is the state of keys rejected by the master
+	KeyStateRejected KeyState = "minions_rejected"
+	// KeyStateDenied is the state of keys denied because a key with the same ID was already accepted
+	KeyStateDenied KeyState = "minions_denied"
+	// KeyStateDeleted is used for keys which do not exist on the master
+	KeyStateDeleted KeyState = ""
+)
+
+// KeyChange describes a minion key which changed state
+type KeyChange struct {
+	ID   string
+	From KeyState
+	To   KeyState
+}
+
+// KeyActionOptions select which keys besides the pending ones are affected by AcceptKeys() and RejectKeys()
+type KeyActionOptions struct {
+	// IncludeAccepted allows RejectKeys() to reject accepted keys
+	IncludeAccepted bool
+
+	// IncludeRejected allows AcceptKeys() to accept rejected keys
+	IncludeRejected bool
+
+	// IncludeDenied allows accepting or rejecting denied keys
+	IncludeDenied bool
+}
+
+// KeyFingerprints contains fingerprints of keys on the master per state
+type KeyFingerprints struct {
+	Local           map[string]string `json:"local"`
+	MinionsRejected map[string]string `json:"minions_rejected"`
+	MinionsDenied   map[string]string `json:"minions_denied"`
+	MinionsPre      map[string]string `json:"minions_pre"`
+	Minions         map[string]string `json:"minions"`
+}
+
+/*
+AcceptKeys accepts pending keys matching the glob pattern or comma separated list of IDs
+
+Returns keys which changed state.
+
+https://docs.saltstack.com/en/latest/ref/wheel/all/salt.wheel.key.html#salt.wheel.key.accept
+*/
+func (c *Client) AcceptKeys(ctx context.Context, match string, opts KeyActionOptions) ([]KeyChange, error) {
+	return c.changeKeys(ctx, "key.accept", map[string]interface{}{
+		"match":            match,
+		"include_rejected": opts.IncludeRejected,
+		"include_denied":   opts.IncludeDenied,
+	}, nil)
+}
+
+/*
+AcceptKeyDict accepts keys listed per state (e.g.: KeyResult{MinionsPre: []string{"minion1"}})
+
+Returns keys which changed state.
+
+https://docs.saltstack.com/en/latest/ref/wheel/all/salt.wheel.key.html#salt.wheel.key.accept_dict
+*/
+func (c *Client) AcceptKeyDict(ctx context.Context, keys KeyResult, opts KeyActionOptions) ([]KeyChange, error) {
+	return c.changeKeys(ctx, "key.accept_dict", map[string]interface{}{
+		"match":            keyDict(keys),
+		"include_rejected": opts.IncludeRejected,
+		"include_denied":   opts.IncludeDenied,
+	}, nil)
+}
+
+/*
+RejectKeys rejects pending keys matching the glob pattern or comma separated list of IDs
+
+Returns keys which changed state.
+
+https://docs.saltstack.com/en/latest/ref/wheel/all/salt.wheel.key.html#salt.wheel.key.reject
+*/
+func (c *Client) RejectKeys(ctx context.Context, match string, opts KeyActionOptions) ([]KeyChange, error) {
+	return c.changeKeys(ctx, "key.reject", map[string]interface{}{
+		"match":            match,
+		"include_accepted": opts.IncludeAccepted,
+		"include_denied":   opts.IncludeDenied,
+	}, nil)
+}
+
+/*
+DeleteKeys deletes keys in any state matching the glob pattern or comma separated list of IDs
+
+Returns keys which were deleted.
+
+https://docs.saltstack.com/en/latest/ref/wheel/all/salt.wheel.key.html#salt.wheel.key.delete
+*/
+func (c *Client) DeleteKeys(ctx context.Context, match string) ([]KeyChange, error) {
+	// key.delete does not return the deleted keys so they are matched beforehand
+	res, err := c.runKeyCommand(ctx, "key.name_match", map[string]interface{}{
+		"match": match,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var matched map[string][]string
+	if err := res.Decode(&matched); err != nil {
+		return nil, fmt.Errorf("key.name_match: %w", err)
+	}
+
+	return c.changeKeys(ctx, "key.delete", map[string]interface{}{
+		"match": match,
+	}, matched)
+}
+
+/*
+KeyFingerprints returns fingerprints of keys matching the glob pattern or comma separated list of IDs
+
+hashType selects the hash algorithm (e.g.: sha256); the default of the master is used if empty.
+
+https://docs.saltstack.com/en/latest/ref/wheel/all/salt.wheel.key.html#salt.wheel.key.finger
+*/
+func (c *Client) KeyFingerprints(ctx context.Context, match string, hashType string) (*KeyFingerprints, error) {
+	kwargs := map[string]interface{}{
+		"match": match,
+	}
+
+	if hashType != "" {
+		kwargs["hash_type"] = hashType
+	}
+
+	res, err := c.runKeyCommand(ctx, "key.finger", kwargs)
+	if err != nil {
+		return nil, err
+	}
+
+	var fingerprints KeyFingerprints
+	if err := res.Decode(&fingerprints); err != nil {
+		return nil, err
+	}
+
+	return &fingerprints, nil
+}
+
+/*
+changeKeys runs the wheel function and compares keys before and after it
+
+Only matched keys are compared so changes made by others at the same time are not reported.
+Keys returned by the function are used if matched is nil.
+*/
+func (c *Client) changeKeys(ctx context.Context, function string, kwargs map[string]interface{}, matched map[string][]string) ([]KeyChange, error) {
+	before, err := c.Keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.runKeyCommand(ctx, function, kwargs)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := c.Keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if matched == nil {
+		if err := res.Decode(&matched); err != nil {
+			return nil, fmt.Errorf("%s: %w", function, err)
+		}
+	}
+
+	from := keyStates(before)
+	to := keyStates(after)
+
+	changes := []KeyChange{}
+	for _, ids := range matched {
+		for _, id := range ids {
+			if from[id] != to[id] {
+				changes = append(changes, KeyChange{ID: id, From: from[id], To: to[id]})
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].ID < changes[j].ID
+	})
+
+	return changes, nil
+}
+
+func (c *Client) runKeyCommand(ctx context.Context, function string, kwargs map[string]interface{}) (*MasterResult, error) {
+	res, err := c.RunWheelCommand(ctx, Command{
+		Function:    function,
+		KWArguments: kwargs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !res.Success {
+		return nil, fmt.Errorf("%s failed: %v", function, res.Return)
+	}
+
+	return res, nil
+}
+
+// keyStates maps minion IDs to the state of their keys
+func keyStates(keys *KeyResult) map[string]KeyState {
+	states := make(map[string]KeyState)
+	for state, ids := range keyDict(*keys) {
+		for _, id := range ids {
+			states[id] = KeyState(state)
+		}
+	}
+
+	return states
+}
+
+// keyDict converts minion keys to the dictionary used by wheel functions; master keys are left out
+func keyDict(keys KeyResult) map[string][]string {
+	d := make(map[string][]string)
+	for state, ids := range map[KeyState][]string{
+		KeyStateAccepted: keys.Minions,
+		KeyStatePending:  keys.MinionsPre,
+		KeyStateRejected: keys.MinionsRejected,
+		KeyStateDenied:   keys.MinionsDenied,
+	} {
+		if len(ids) > 0 {
+			d[string(state)] = ids
+		}
+	}
+
+	return d
+}
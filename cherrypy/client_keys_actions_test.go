@@ -0,0 +1,84 @@
+package cherrypy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptKeys(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	setupSequence(t, tester, "keys_list", "success", "pending_accepted")
+	tester.Setup(t, "run", "wheel_key_accept")
+
+	res, err := c.AcceptKeys(context.Background(), "saltmaster.*", KeyActionOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []KeyChange{{ID: "saltmaster.local", From: KeyStatePending, To: KeyStateAccepted}}, res)
+}
+
+func TestAcceptKeysUnchanged(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	setupSequence(t, tester, "keys_list", "pending_accepted")
+	tester.Setup(t, "run", "wheel_key_accept")
+
+	res, err := c.AcceptKeys(context.Background(), "saltmaster.*", KeyActionOptions{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, res)
+}
+
+func TestAcceptKeyDict(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	setupSequence(t, tester, "keys_list", "success", "pending_accepted")
+	tester.Setup(t, "run", "wheel_key_accept_dict")
+
+	res, err := c.AcceptKeyDict(context.Background(), KeyResult{MinionsPre: []string{"saltmaster.local"}}, KeyActionOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []KeyChange{{ID: "saltmaster.local", From: KeyStatePending, To: KeyStateAccepted}}, res)
+}
+
+func TestRejectKeys(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	setupSequence(t, tester, "keys_list", "success", "pending_rejected")
+	tester.Setup(t, "run", "wheel_key_reject")
+
+	res, err := c.RejectKeys(context.Background(), "saltmaster.local", KeyActionOptions{IncludeDenied: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []KeyChange{{ID: "saltmaster.local", From: KeyStatePending, To: KeyStateRejected}}, res)
+}
+
+func TestDeleteKeys(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	setupSequence(t, tester, "keys_list", "success", "minion1_deleted")
+	calls := setupSequence(t, tester, "run", "wheel_arguments", "wheel_key_delete")
+
+	res, err := c.DeleteKeys(context.Background(), "minion1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, []KeyChange{{ID: "minion1", From: KeyStateAccepted, To: KeyStateDeleted}}, res)
+}
+
+func TestKeyFingerprints(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "wheel_key_finger")
+
+	res, err := c.KeyFingerprints(context.Background(), "*", "sha256")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "b2:96:7c:28:2a:91:0a:7f:7a:8e:de:c1:dd:dd:cc:83:49:4f:ab:a9:a8:91:f8:80:19:2b:b8:e1:ec:9b:e5:57", res.Minions["minion1"])
+	assert.Len(t, res.Minions, 2)
+	assert.Len(t, res.MinionsPre, 1)
+	assert.Len(t, res.Local, 2)
+	assert.Empty(t, res.MinionsRejected)
+}
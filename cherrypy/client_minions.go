@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 )
 
 var (
@@ -16,7 +15,7 @@ var (
 // Minion information
 type Minion struct {
 	ID     string
-	Grains map[string]interface{}
+	Grains Grains
 }
 
 // MinionJob contains job information to be sent to the minion
@@ -104,7 +103,7 @@ func (c *Client) SubmitJobs(ctx context.Context, jobs []MinionJob) ([]AsyncMinio
 		return nil, err
 	}
 
-	log.Println("[DEBUG] Sending submit minion job request")
+	c.debug("Sending submit minion job request", Field{"jobs", len(jobs)})
 	var resp submitMinionJobResponse
 	_, err = c.do(req, &resp)
 	if err != nil {
@@ -138,7 +137,7 @@ func (c *Client) getMinions(ctx context.Context, id string) ([]Minion, error) {
 		return nil, err
 	}
 
-	log.Println("[DEBUG] Sending minion details request")
+	c.debug("Sending minion details request", Field{"minion", id})
 	var resp minionDetailResponse
 	_, err = c.do(req, &resp)
 	if err != nil {
@@ -157,7 +156,7 @@ func (c *Client) getMinions(ctx context.Context, id string) ([]Minion, error) {
 		minions[i] = Minion{ID: k}
 
 		// Grains are not returned for offline minions
-		var g map[string]interface{}
+		var g Grains
 		if json.Unmarshal(m, &g) == nil {
 			minions[i].Grains = g
 		}
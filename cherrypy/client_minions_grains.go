@@ -0,0 +1,90 @@
+package cherrypy
+
+// Grains contain static information about a minion; custom grains are kept alongside core grains
+type Grains map[string]interface{}
+
+/*
+CoreGrains contain commonly used grains collected by Salt on every minion
+
+Grains missing on the minion or having an unexpected type are left empty.
+
+https://docs.saltstack.com/en/latest/ref/grains/all/salt.grains.core.html
+*/
+type CoreGrains struct {
+	ID       string `json:"id"`
+	Host     string `json:"host"`
+	FQDN     string `json:"fqdn"`
+	Domain   string `json:"domain"`
+	NodeName string `json:"nodename"`
+
+	OS             string `json:"os"`
+	OSFamily       string `json:"os_family"`
+	OSFullName     string `json:"osfullname"`
+	OSRelease      string `json:"osrelease"`
+	OSMajorRelease int    `json:"osmajorrelease"`
+	OSCodename     string `json:"oscodename"`
+	OSArch         string `json:"osarch"`
+	OSFinger       string `json:"osfinger"`
+
+	Kernel        string `json:"kernel"`
+	KernelRelease string `json:"kernelrelease"`
+	KernelVersion string `json:"kernelversion"`
+	Init          string `json:"init"`
+
+	CPUArch  string `json:"cpuarch"`
+	CPUModel string `json:"cpu_model"`
+	NumCPUs  int    `json:"num_cpus"`
+
+	// MemTotal and SwapTotal are in MiB
+	MemTotal  int `json:"mem_total"`
+	SwapTotal int `json:"swap_total"`
+
+	IPv4             []string            `json:"ipv4"`
+	IPv6             []string            `json:"ipv6"`
+	IPInterfaces     map[string][]string `json:"ip_interfaces"`
+	IP4Interfaces    map[string][]string `json:"ip4_interfaces"`
+	IP6Interfaces    map[string][]string `json:"ip6_interfaces"`
+	HWAddrInterfaces map[string]string   `json:"hwaddr_interfaces"`
+
+	Virtual      string `json:"virtual"`
+	Manufacturer string `json:"manufacturer"`
+	ProductName  string `json:"productname"`
+	SerialNumber string `json:"serialnumber"`
+	UUID         string `json:"uuid"`
+	MachineID    string `json:"machine_id"`
+
+	SaltVersion string `json:"saltversion"`
+}
+
+/*
+Get returns the value at the colon delimited path (e.g.: ip_interfaces:eth0)
+
+Nested dictionaries and lists are traversed like grain targets; numeric keys are used as list indexes.
+*/
+func (g Grains) Get(path string) (interface{}, bool) {
+	return g.GetDelimited(path, defaultTargetDelimiter)
+}
+
+// GetDelimited returns the value at the path using a custom delimiter (e.g.: systemd%version)
+func (g Grains) GetDelimited(path string, delimiter string) (interface{}, bool) {
+	if g == nil {
+		return nil, false
+	}
+
+	return traverseData(g, path, delimiter)
+}
+
+// Decode stores grains in the value pointed to by v (e.g.: a struct with json tags)
+func (g Grains) Decode(v interface{}) error {
+	return decodeValue(g, v)
+}
+
+// Core returns core grains; see CoreGrains
+func (g Grains) Core() CoreGrains {
+	var core CoreGrains
+
+	// Fields with unexpected types are skipped while others are still decoded
+	_ = g.Decode(&core)
+
+	return core
+}
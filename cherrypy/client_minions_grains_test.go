@@ -0,0 +1,125 @@
+package cherrypy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMinionCoreGrains(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "minions_get", "success")
+
+	res, err := c.Minion(context.Background(), "minion1")
+	assert.NoError(t, err)
+
+	core := res.Grains.Core()
+
+	assert.Equal(t, "minion1", core.ID)
+	assert.Equal(t, "Ubuntu", core.OS)
+	assert.Equal(t, "Debian", core.OSFamily)
+	assert.Equal(t, "18.04", core.OSRelease)
+	assert.Equal(t, 18, core.OSMajorRelease)
+	assert.Equal(t, 1, core.NumCPUs)
+	assert.Equal(t, 985, core.MemTotal)
+	assert.Equal(t, []string{"10.0.2.15", "127.0.0.1", "192.168.50.11"}, core.IPv4)
+	assert.Equal(t, []string{"10.0.2.15", "fe80::a00:27ff:febc:dca4"}, core.IPInterfaces["eth0"])
+	assert.Equal(t, "VirtualBox", core.Virtual)
+	assert.Equal(t, "2019.2.3", core.SaltVersion)
+}
+
+func TestOfflineMinionCoreGrains(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "minions_get", "offline")
+
+	res, err := c.Minion(context.Background(), "minion2")
+	assert.NoError(t, err)
+
+	v, ok := res.Grains.Get("os")
+
+	assert.Equal(t, CoreGrains{}, res.Grains.Core())
+	assert.False(t, ok)
+	assert.Nil(t, v)
+}
+
+func TestCoreGrainsUnexpectedTypes(t *testing.T) {
+	g := Grains{
+		"os":        "Ubuntu",
+		"num_cpus":  "many",
+		"ipv4":      "10.0.0.1",
+		"mem_total": 2048.0,
+	}
+
+	core := g.Core()
+
+	assert.Equal(t, "Ubuntu", core.OS)
+	assert.Equal(t, 0, core.NumCPUs)
+	assert.Nil(t, core.IPv4)
+	assert.Equal(t, 2048, core.MemTotal)
+}
+
+func TestGrainsGet(t *testing.T) {
+	g := Grains{
+		"os": "Ubuntu",
+		"ip_interfaces": map[string]interface{}{
+			"eth0": []interface{}{"10.0.2.15", "fe80::a00:27ff:febc:dca4"},
+		},
+		"gpus": []interface{}{
+			map[string]interface{}{"model": "VirtualBox Graphics Adapter", "vendor": "unknown"},
+		},
+		"systemd": map[string]interface{}{"version": "237"},
+	}
+
+	cases := []struct {
+		path     string
+		expected interface{}
+		ok       bool
+	}{
+		{"os", "Ubuntu", true},
+		{"ip_interfaces:eth0", []interface{}{"10.0.2.15", "fe80::a00:27ff:febc:dca4"}, true},
+		{"ip_interfaces:eth0:1", "fe80::a00:27ff:febc:dca4", true},
+		{"ip_interfaces:eth0:-1", "fe80::a00:27ff:febc:dca4", true},
+		{"ip_interfaces:eth0:2", nil, false},
+		{"gpus:vendor", "unknown", true},
+		{"gpus:0:model", "VirtualBox Graphics Adapter", true},
+		{"os:Ubuntu", nil, false},
+		{"missing", nil, false},
+	}
+
+	for _, tc := range cases {
+		v, ok := g.Get(tc.path)
+
+		assert.Equal(t, tc.ok, ok, tc.path)
+		assert.Equal(t, tc.expected, v, tc.path)
+	}
+
+	v, ok := g.GetDelimited("systemd%version", "%")
+
+	assert.True(t, ok)
+	assert.Equal(t, "237", v)
+}
+
+func TestGrainsDecode(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "minions_get", "success")
+
+	res, err := c.Minion(context.Background(), "minion1")
+	assert.NoError(t, err)
+
+	var custom struct {
+		Systemd struct {
+			Version string `json:"version"`
+		} `json:"systemd"`
+		SaltVersionInfo []int `json:"saltversioninfo"`
+	}
+
+	err = res.Grains.Decode(&custom)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "237", custom.Systemd.Version)
+	assert.Equal(t, []int{2019, 2, 3, 0}, custom.SaltVersionInfo)
+}
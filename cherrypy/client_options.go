@@ -0,0 +1,169 @@
+package cherrypy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var (
+	// ErrorInvalidCABundle indicates no certificates could be parsed from the CA bundle
+	ErrorInvalidCABundle = errors.New("no certificates found in CA bundle")
+
+	// ErrorTransportOptionConflict indicates transport options were used together with a custom HTTP client
+	ErrorTransportOptionConflict = errors.New("TLS and proxy options cannot be used with a custom HTTP client")
+)
+
+// Option configures a Client created with NewClientWithOptions()
+type Option func(*clientOptions) error
+
+type clientOptions struct {
+	eauth      eauth
+	httpClient *http.Client
+	tlsConfig  *tls.Config
+	proxy      func(*http.Request) (*url.URL, error)
+	timeout    time.Duration
+	userAgent  string
+	logger     Logger
+	retry      *RetryPolicy
+}
+
+func (o *clientOptions) tls() *tls.Config {
+	if o.tlsConfig == nil {
+		o.tlsConfig = &tls.Config{}
+	}
+
+	return o.tlsConfig
+}
+
+/*
+NewClientWithOptions creates a new instance of client configured with the options provided
+  address: URL of the cherrypy instance on a master (e.g.: https://salt-master:8000)
+
+Unless WithProxy() or WithHTTPClient() is used, proxies are selected from the environment (see http.ProxyFromEnvironment).
+
+Example usage:
+	client, err := cherrypy.NewClientWithOptions("https://master:8000",
+		cherrypy.WithCredentials("admin", "password", "pam"),
+		cherrypy.WithCABundle(pem),
+		cherrypy.WithTimeout(30*time.Second),
+	)
+*/
+func NewClientWithOptions(address string, opts ...Option) (*Client, error) {
+	var o clientOptions
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+
+	client := o.httpClient
+	if client == nil {
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		if o.tlsConfig != nil {
+			tr.TLSClientConfig = o.tlsConfig
+		}
+
+		if o.proxy != nil {
+			tr.Proxy = o.proxy
+		}
+
+		client = &http.Client{Transport: tr}
+	} else if o.tlsConfig != nil || o.proxy != nil {
+		return nil, ErrorTransportOptionConflict
+	}
+
+	a := o.eauth
+	return &Client{
+		client:    client,
+		eauth:     &a,
+		timeout:   o.timeout,
+		userAgent: o.userAgent,
+		logger:    o.logger,
+		retry:     o.retry,
+		Address:   address,
+		loginSem:  make(chan struct{}, 1),
+		loginCh:   make(chan struct{}),
+	}, nil
+}
+
+/*
+WithCredentials sets credentials used to authenticate with Salt
+  backend: External authentication (eauth) backend (https://docs.saltstack.com/en/latest/topics/eauth/index.html)
+*/
+func WithCredentials(username string, password string, backend string) Option {
+	return func(o *clientOptions) error {
+		o.eauth = eauth{
+			Username: username,
+			Password: password,
+			Backend:  backend,
+		}
+
+		return nil
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send requests. Cannot be combined with TLS or proxy options.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *clientOptions) error {
+		o.httpClient = client
+		return nil
+	}
+}
+
+// WithInsecureSkipVerify disables verification of the certificate presented by the master
+func WithInsecureSkipVerify(skip bool) Option {
+	return func(o *clientOptions) error {
+		o.tls().InsecureSkipVerify = skip
+		return nil
+	}
+}
+
+// WithCABundle sets PEM encoded certificates of authorities trusted to sign the master's certificate
+func WithCABundle(pem []byte) Option {
+	return func(o *clientOptions) error {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return ErrorInvalidCABundle
+		}
+
+		o.tls().RootCAs = pool
+		return nil
+	}
+}
+
+// WithClientCertificate sets the certificate presented to the master for mutual TLS
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(o *clientOptions) error {
+		c := o.tls()
+		c.Certificates = append(c.Certificates, cert)
+		return nil
+	}
+}
+
+// WithProxy sets the function that selects the proxy for a request (e.g.: http.ProxyURL)
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) Option {
+	return func(o *clientOptions) error {
+		o.proxy = proxy
+		return nil
+	}
+}
+
+// WithTimeout limits the time a request may take. Event subscriptions are not affected.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *clientOptions) error {
+		o.timeout = timeout
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with requests
+func WithUserAgent(userAgent string) Option {
+	return func(o *clientOptions) error {
+		o.userAgent = userAgent
+		return nil
+	}
+}
@@ -0,0 +1,110 @@
+package cherrypy
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClientWithOptions(t *testing.T) {
+	tester, _ := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "auth_login", "success")
+
+	c, err := NewClientWithOptions(tester.URL, WithCredentials(testUsername, testPassword, testEAuth))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Login(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, testToken, c.Token())
+}
+
+func TestEnvironmentProxy(t *testing.T) {
+	c, err := NewClientWithOptions("http://master:8000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotNil(t, c.client.Transport.(*http.Transport).Proxy)
+
+	c = NewClient("http://master:8000", testUsername, testPassword, testEAuth, false)
+	assert.Nil(t, c.client.Transport.(*http.Transport).Proxy)
+}
+
+func TestUserAgentOption(t *testing.T) {
+	tester, _ := setup(t)
+	defer tester.Close()
+	tester.Do("/stats", func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "salt-tests/1.0", req.Header.Get("User-Agent"))
+		w.Write([]byte("{}"))
+	})
+
+	c, err := NewClientWithOptions(tester.URL, WithUserAgent("salt-tests/1.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Stats(context.Background())
+
+	assert.NoError(t, err)
+}
+
+func TestTimeoutOption(t *testing.T) {
+	tester, _ := setup(t)
+	defer tester.Close()
+	tester.Do("/stats", func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+
+	c, err := NewClientWithOptions(tester.URL, WithTimeout(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Stats(context.Background())
+
+	assert.Error(t, err)
+}
+
+func TestCABundleOption(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	c, err := NewClientWithOptions(server.URL, WithCABundle(bundle))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Stats(context.Background())
+	assert.NoError(t, err)
+
+	c, err = NewClientWithOptions(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Stats(context.Background())
+	assert.Error(t, err)
+}
+
+func TestInvalidOptions(t *testing.T) {
+	_, err := NewClientWithOptions("https://master:8000", WithCABundle([]byte("invalid")))
+	assert.Equal(t, ErrorInvalidCABundle, err)
+
+	_, err = NewClientWithOptions("https://master:8000", WithHTTPClient(http.DefaultClient), WithInsecureSkipVerify(true))
+	assert.Equal(t, ErrorTransportOptionConflict, err)
+}
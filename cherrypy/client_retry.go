@@ -0,0 +1,171 @@
+package cherrypy
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type retryContextKey struct{}
+
+// RetryPolicy controls how requests failing with transient errors are retried
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first one. Values below 2 disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry
+	InitialBackoff time.Duration
+
+	// MaxBackoff limits the delay between attempts
+	MaxBackoff time.Duration
+
+	// Multiplier increases the delay after each attempt; 2 is used if not set
+	Multiplier float64
+
+	// Jitter randomizes each delay by up to the given fraction (e.g.: 0.2 for ±20%)
+	Jitter float64
+
+	// RetryableStatusCodes lists HTTP status codes considered transient
+	RetryableStatusCodes []int
+
+	// RetryableError decides whether a transport error is transient.
+	// If not set, errors of the HTTP client are retried unless the request was cancelled or timed out.
+	// Errors decoding the response or returned by callbacks such as the one of RunBatch() are never retried.
+	RetryableError func(error) bool
+
+	// RetryNonIdempotent allows retrying requests which might not be safe to repeat such as RunCommands() and SubmitJobs().
+	// Use AllowRetry() to allow retrying individual calls instead.
+	RetryNonIdempotent bool
+}
+
+/*
+DefaultRetryPolicy returns a policy retrying idempotent requests up to 3 times
+when the master is unreachable or responds with 502, 503 or 504
+*/
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryableStatusCodes: []int{
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// WithRetryPolicy sets the policy used to retry requests failing with transient errors
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *clientOptions) error {
+		o.retry = &policy
+		return nil
+	}
+}
+
+/*
+AllowRetry marks requests made with the returned context as safe to retry
+
+Requests with GET method are always retried according to the policy;
+other requests such as RunCommands() and SubmitJobs() are only retried if the context is marked.
+*/
+func AllowRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryContextKey{}, true)
+}
+
+func (p *RetryPolicy) allows(req *http.Request) bool {
+	if p == nil || p.MaxAttempts < 2 {
+		return false
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	allowed, _ := req.Context().Value(retryContextKey{}).(bool)
+	return allowed || p.RetryNonIdempotent
+}
+
+// retryableStatus reports whether the status code is listed as transient by the policy
+func (p *RetryPolicy) retryableStatus(code int) bool {
+	if p == nil {
+		return false
+	}
+
+	for _, c := range p.RetryableStatusCodes {
+		if code == c {
+			return true
+		}
+	}
+
+	return false
+}
+
+// transient reports whether an error of the transport is worth retrying
+func (p *RetryPolicy) transient(err error) bool {
+	if p == nil {
+		return false
+	}
+
+	if p.RetryableError != nil {
+		return p.RetryableError(err)
+	}
+
+	var uerr *url.Error
+	return errors.As(err, &uerr) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// backoff returns the delay before the given retry; first retry is 1
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(retry-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(d)
+}
+
+// send sends the request and retries it according to the retry policy
+func (c *Client) send(req *http.Request, v interface{}) (*http.Response, error) {
+	p := c.retry
+	if !p.allows(req) {
+		return c.sendOnce(req, v)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, retryable, err := c.attempt(req, v)
+		if err == nil || !retryable || attempt >= p.MaxAttempts || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		delay := p.backoff(attempt)
+		c.warn("Retrying request", Field{"method", req.Method}, Field{"endpoint", req.URL.Path}, Field{"attempt", attempt}, Field{"delay", delay}, Field{"error", err})
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+
+		req, err = rewindRequest(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
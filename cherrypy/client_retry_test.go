@@ -0,0 +1,155 @@
+package cherrypy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	neturl "net/url"
+	"testing"
+	"time"
+
+	apiTester "github.com/finarfin/go-apiclient-tester/tester"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRetry(t *testing.T, tester *apiTester.Tester, category string, scenario string, failures int) *int {
+	s, err := tester.Scenario(category, scenario)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	tester.Do(s.Request.Path, func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		apiTester.WriteResponse(t, &s.Response, w)
+	})
+
+	return &calls
+}
+
+func newRetryClient(t *testing.T, url string) *Client {
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+
+	c, err := NewClientWithOptions(url,
+		WithCredentials(testUsername, testPassword, testEAuth),
+		WithRetryPolicy(policy),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.SetToken(testToken)
+	return c
+}
+
+func TestRetryIdempotentRequest(t *testing.T) {
+	tester, _ := setup(t)
+	defer tester.Close()
+	calls := setupRetry(t, tester, "stats", "success", 2)
+
+	c := newRetryClient(t, tester.URL)
+	res, err := c.Stats(context.Background())
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res)
+	assert.Equal(t, 3, *calls)
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	tester, _ := setup(t)
+	defer tester.Close()
+	calls := setupRetry(t, tester, "stats", "success", 3)
+
+	c := newRetryClient(t, tester.URL)
+	_, err := c.Stats(context.Background())
+
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, err.(*RequestError).StatusCode)
+	assert.Equal(t, 3, *calls)
+}
+
+func TestRetryNonIdempotentRequest(t *testing.T) {
+	tester, _ := setup(t)
+	defer tester.Close()
+	calls := setupRetry(t, tester, "run", "local_success", 1)
+
+	cmd := Command{
+		Client:   LocalClient,
+		Target:   ExpressionTarget{Expression: "minion1", Type: Glob},
+		Function: "test.ping",
+	}
+
+	c := newRetryClient(t, tester.URL)
+	_, err := c.RunCommand(context.Background(), cmd)
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, *calls)
+
+	res, err := c.RunCommand(AllowRetry(context.Background()), cmd)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, res)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	}
+
+	assert.Equal(t, 100*time.Millisecond, p.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, p.backoff(2))
+	assert.Equal(t, 800*time.Millisecond, p.backoff(4))
+	assert.Equal(t, time.Second, p.backoff(5))
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.backoff(1)
+		assert.True(t, d >= 50*time.Millisecond && d <= 150*time.Millisecond)
+	}
+}
+
+func TestRetryInvalidResponse(t *testing.T) {
+	tester, _ := setup(t)
+	defer tester.Close()
+
+	calls := 0
+	tester.Do("/stats", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"CherryPy Applications": `))
+	})
+
+	c := newRetryClient(t, tester.URL)
+	_, err := c.Stats(context.Background())
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryTransportError(t *testing.T) {
+	tester, _ := setup(t)
+	url := tester.URL
+	tester.Close()
+
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+
+	assert.True(t, policy.transient(&neturl.Error{Op: "Get", URL: url, Err: errors.New("connection refused")}))
+	assert.False(t, policy.transient(&neturl.Error{Op: "Get", URL: url, Err: context.DeadlineExceeded}))
+	assert.False(t, policy.transient(&neturl.Error{Op: "Get", URL: url, Err: context.Canceled}))
+	assert.False(t, policy.transient(errors.New("unexpected token in response")))
+
+	c := newRetryClient(t, url)
+	_, err := c.Stats(context.Background())
+
+	var uerr *neturl.Error
+	assert.True(t, errors.As(err, &uerr), "%v", err)
+}
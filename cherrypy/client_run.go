@@ -2,8 +2,10 @@ package cherrypy
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"math"
+	"time"
 )
 
 /*
@@ -26,14 +28,53 @@ const (
 	// WheelClient invokes wheel modules on the Master.
 	// Wheel modules do not have a direct CLI equivalent
 	WheelClient = "wheel"
+
+	// LocalAsyncClient sends commands to Minions without waiting for their returns.
+	// Equivalent to the salt CLI command with --async flag.
+	LocalAsyncClient CommandClient = "local_async"
+
+	// RunnerAsyncClient invokes runner modules on the Master without waiting for them to complete.
+	// Equivalent to the salt-run CLI command with --async flag.
+	RunnerAsyncClient CommandClient = "runner_async"
+
+	// WheelAsyncClient invokes wheel modules on the Master without waiting for them to complete
+	WheelAsyncClient CommandClient = "wheel_async"
+)
+
+var (
+	// ErrorFullReturnConflict indicates full_return was set in Arguments with a value the client of the command cannot send
+	ErrorFullReturnConflict = errors.New("full_return is set by the client")
 )
 
-// Command to send to Run endpont
+/*
+Command to send to Run endpont
+
+Batches are not configured by the command; use RunBatch() with BatchOptions to run it with the local_batch client.
+*/
 type Command struct {
-	Client     CommandClient
-	Target     Target
-	Function   string
-	Arguments  map[string]interface{}
+	Client   CommandClient
+	Target   Target
+	Function string
+
+	// PositionalArguments are sent as positional arguments (arg) of the function
+	PositionalArguments []interface{}
+
+	// KWArguments are sent as keyword arguments (kwarg) of the function
+	KWArguments map[string]interface{}
+
+	// Timeout for the master to wait for minions (local, ssh) or the function to complete (runner).
+	// Sent in seconds; not used by the wheel client.
+	Timeout time.Duration
+
+	// Returner sends minion returns to the returner in addition to the master (local clients only)
+	Returner string
+
+	// SSH configures connections made by the ssh client
+	SSH *SSHOptions
+
+	// Arguments are merged into the lowstate as is. Used for keys which do not have a dedicated field.
+	// full_return is sent as true by the local and runner clients and is not accepted by the others.
+	Arguments map[string]interface{}
 }
 
 type runResponse struct {
@@ -43,6 +84,8 @@ type runResponse struct {
 /*
 RunCommand runs a command on master using Run endpoint
 
+Returns are not interpreted; use ParseLocalResult() or RunLocalCommand() to classify the outcome per minion.
+
 https://docs.saltstack.com/en/latest/ref/netapi/all/salt.netapi.rest_cherrypy.html#salt.netapi.rest_cherrypy.app.Run
 */
 func (c *Client) RunCommand(ctx context.Context, cmd Command) (interface{}, error) {
@@ -66,31 +109,11 @@ https://docs.saltstack.com/en/latest/ref/netapi/all/salt.netapi.rest_cherrypy.ht
 func (c *Client) RunCommands(ctx context.Context, cmds []Command) ([]interface{}, error) {
 	r := make([]map[string]interface{}, len(cmds))
 	for i, v := range cmds {
-		d := make(map[string]interface{})
-		
-		if v.Arguments != nil {
-			for k, a := range v.Arguments {
-				d[k] = a
-			}
+		d, err := c.lowstate(v)
+		if err != nil {
+			return nil, err
 		}
 
-		d["client"] = v.Client
-		d["fun"] = v.Function
-		d["username"] = c.eauth.Username
-		d["password"] = c.eauth.Password
-		d["eauth"] = c.eauth.Backend
-
-		if v.Target != nil {
-			d["tgt"] = v.Target.GetTarget()
-			d["tgt_type"] = v.Target.GetType()
-		}
-
-		// wheel throws following error if full_return is sent as a seperate argument
-		// TypeError: call_func() got multiple values for keyword argument 'full_return'
-		if v.Client != WheelClient {
-			d["full_return"] = true
-		}
-		
 		r[i] = d
 	}
 
@@ -99,7 +122,7 @@ func (c *Client) RunCommands(ctx context.Context, cmds []Command) ([]interface{}
 		return nil, err
 	}
 
-	log.Println("[DEBUG] Sending run jobs request")
+	c.debug("Sending run jobs request", Field{"commands", len(cmds)})
 	var resp runResponse
 	_, err = c.do(req, &resp)
 	if err != nil {
@@ -108,3 +131,98 @@ func (c *Client) RunCommands(ctx context.Context, cmds []Command) ([]interface{}
 
 	return resp.Return, nil
 }
+
+// lowstate converts the command to the data structure accepted by Run endpoint
+func (c *Client) lowstate(cmd Command) (map[string]interface{}, error) {
+	d := make(map[string]interface{})
+
+	if cmd.Arguments != nil {
+		for k, a := range cmd.Arguments {
+			d[k] = a
+		}
+	}
+
+	d["client"] = cmd.Client
+	d["fun"] = cmd.Function
+	d["username"] = c.eauth.Username
+	d["password"] = c.eauth.Password
+	d["eauth"] = c.eauth.Backend
+
+	if cmd.Target != nil {
+		d["tgt"] = cmd.Target.GetTarget()
+		d["tgt_type"] = cmd.Target.GetType()
+	}
+
+	switch cmd.Client {
+	case WheelClient, WheelAsyncClient:
+		// wheel uses arg and kwarg only if both are present;
+		// otherwise top-level keys are matched with the function's arguments
+		if cmd.PositionalArguments != nil || cmd.KWArguments != nil {
+			d["arg"] = nonNilArgs(cmd.PositionalArguments)
+			d["kwarg"] = nonNilKWArgs(cmd.KWArguments)
+		}
+	default:
+		if cmd.PositionalArguments != nil {
+			d["arg"] = cmd.PositionalArguments
+		}
+
+		if cmd.KWArguments != nil {
+			d["kwarg"] = cmd.KWArguments
+		}
+	}
+
+	if cmd.Timeout > 0 && (cmd.Client == LocalClient || cmd.Client == LocalAsyncClient || cmd.Client == LocalBatchClient || cmd.Client == RunnerClient || cmd.Client == SSHClient) {
+		d["timeout"] = int(math.Ceil(cmd.Timeout.Seconds()))
+	}
+
+	if cmd.Returner != "" && (cmd.Client == LocalClient || cmd.Client == LocalAsyncClient || cmd.Client == LocalBatchClient) {
+		d["ret"] = cmd.Returner
+	}
+
+	if cmd.SSH != nil && cmd.Client == SSHClient {
+		cmd.SSH.lowstate(d)
+	}
+
+	fullReturn := true
+	switch cmd.Client {
+	case WheelClient, WheelAsyncClient:
+		// wheel throws following error if full_return is sent as a seperate argument
+		// TypeError: call_func() got multiple values for keyword argument 'full_return'
+		fullReturn = false
+	case LocalAsyncClient, RunnerAsyncClient:
+		// Async clients return job details only; full_return has no meaning for them
+		fullReturn = false
+	case SSHClient:
+		// salt-ssh rejects options which are not in its whitelist
+		fullReturn = false
+	case LocalBatchClient:
+		// Batch returns contain only the return of the function regardless of full_return
+		fullReturn = false
+	}
+
+	if v, ok := d["full_return"]; ok && (!fullReturn || v != true) {
+		return nil, fmt.Errorf("%w: %v with %s client", ErrorFullReturnConflict, v, cmd.Client)
+	}
+
+	if fullReturn {
+		d["full_return"] = true
+	}
+
+	return d, nil
+}
+
+func nonNilArgs(args []interface{}) []interface{} {
+	if args == nil {
+		return []interface{}{}
+	}
+
+	return args
+}
+
+func nonNilKWArgs(kwargs map[string]interface{}) map[string]interface{} {
+	if kwargs == nil {
+		return map[string]interface{}{}
+	}
+
+	return kwargs
+}
@@ -0,0 +1,102 @@
+package cherrypy
+
+import (
+	"sort"
+	"strings"
+)
+
+// Outcome classifies how a function executed on a minion ended
+type Outcome string
+
+const (
+	// OutcomeSuccess indicates the function returned successfully
+	OutcomeSuccess Outcome = "success"
+
+	// OutcomeFailure indicates the function returned with a non-zero retcode or an unsuccessful result
+	OutcomeFailure Outcome = "failure"
+
+	// OutcomeException indicates the function raised an exception on the minion
+	OutcomeException Outcome = "exception"
+
+	// OutcomeNoResponse indicates the minion did not return before the timeout
+	OutcomeNoResponse Outcome = "no_response"
+
+	// OutcomeNotConnected indicates the minion was not connected to the master
+	OutcomeNotConnected Outcome = "not_connected"
+)
+
+const (
+	noResponseMessage   = "Minion did not return. [No response]"
+	notConnectedMessage = "Minion did not return. [Not connected]"
+	exceptionMessage    = "The minion function caused an exception"
+	tracebackMessage    = "Traceback (most recent call last)"
+)
+
+// Unreachable reports whether the minion did not return instead of the function failing
+func (o Outcome) Unreachable() bool {
+	return o == OutcomeNoResponse || o == OutcomeNotConnected
+}
+
+/*
+classifyReturn returns the outcome of a minion return
+
+Salt reports minions which did not return and exceptions raised by functions as strings in place of the return
+(e.g.: "Minion did not return. [No response]"); these are not successful regardless of the retcode.
+*/
+func classifyReturn(ret interface{}, success bool) Outcome {
+	if s, ok := ret.(string); ok {
+		s = strings.TrimSpace(s)
+		switch {
+		case strings.HasPrefix(s, noResponseMessage):
+			return OutcomeNoResponse
+		case strings.HasPrefix(s, notConnectedMessage):
+			return OutcomeNotConnected
+		case strings.HasPrefix(s, exceptionMessage), strings.HasPrefix(s, tracebackMessage):
+			return OutcomeException
+		}
+	}
+
+	if !success {
+		return OutcomeFailure
+	}
+
+	return OutcomeSuccess
+}
+
+// newMinionReturn creates a minion return with its outcome; success is cleared if the outcome is not successful
+func newMinionReturn(jid string, ret interface{}, retcode int, success bool) MinionReturn {
+	outcome := classifyReturn(ret, success)
+
+	return MinionReturn{
+		JID:        jid,
+		Return:     ret,
+		ReturnCode: retcode,
+		Success:    outcome == OutcomeSuccess,
+		Outcome:    outcome,
+	}
+}
+
+// WithOutcome returns minions with the outcome in sorted order
+func (r LocalResult) WithOutcome(outcome Outcome) []string {
+	return minionsWithOutcome(r, outcome)
+}
+
+// Unreachable returns minions which did not return or were not connected in sorted order
+func (r LocalResult) Unreachable() []string {
+	return minionsWithOutcome(r, OutcomeNoResponse, OutcomeNotConnected)
+}
+
+func minionsWithOutcome(results map[string]MinionReturn, outcomes ...Outcome) []string {
+	minions := []string{}
+	for id, r := range results {
+		for _, o := range outcomes {
+			if r.Outcome == o {
+				minions = append(minions, id)
+				break
+			}
+		}
+	}
+
+	sort.Strings(minions)
+	return minions
+}
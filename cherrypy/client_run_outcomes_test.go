@@ -0,0 +1,79 @@
+package cherrypy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunLocalCommandOutcomes(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "local_unreachable")
+
+	res, err := c.RunLocalCommand(context.Background(), Command{
+		Target:   ExpressionTarget{Expression: "*", Type: Glob},
+		Function: "test.ping",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, OutcomeSuccess, res["minion1"].Outcome)
+	assert.True(t, res["minion1"].Success)
+	assert.Equal(t, OutcomeNoResponse, res["minion2"].Outcome)
+	assert.False(t, res["minion2"].Success)
+	assert.Equal(t, OutcomeException, res["minion3"].Outcome)
+	assert.Equal(t, OutcomeNotConnected, res["minion4"].Outcome)
+	assert.Equal(t, OutcomeFailure, res["minion5"].Outcome)
+
+	assert.Equal(t, []string{"minion2", "minion4"}, res.Unreachable())
+	assert.Equal(t, []string{"minion3"}, res.WithOutcome(OutcomeException))
+	assert.Equal(t, []string{"minion5"}, res.WithOutcome(OutcomeFailure))
+}
+
+func TestClassifyReturn(t *testing.T) {
+	cases := []struct {
+		ret     interface{}
+		success bool
+		outcome Outcome
+	}{
+		{true, true, OutcomeSuccess},
+		{"Minion did not return", true, OutcomeSuccess},
+		{false, false, OutcomeFailure},
+		{"'foo.bar' is not available.", false, OutcomeFailure},
+		{"Minion did not return. [No response]", true, OutcomeNoResponse},
+		{"Minion did not return. [Not connected]", false, OutcomeNotConnected},
+		{"The minion function caused an exception: Traceback (most recent call last):\n", false, OutcomeException},
+		{"Traceback (most recent call last):\n  File \"x.py\"\nKeyError: 'a'", true, OutcomeException},
+		{map[string]interface{}{"ret": "Minion did not return. [No response]"}, true, OutcomeSuccess},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.outcome, classifyReturn(tc.ret, tc.success), "%v", tc.ret)
+	}
+
+	assert.True(t, OutcomeNotConnected.Unreachable())
+	assert.False(t, OutcomeException.Unreachable())
+}
+
+func TestJobOutcomes(t *testing.T) {
+	job := &JobDetails{
+		Minions: []string{"minion1", "minion2", "minion3", "minion4"},
+		Results: parseJobResults(testSampleJobID, map[string]jobResult{
+			"minion1": {Return: "Hello"},
+			"minion2": {Return: "Minion did not return. [No response]", ReturnCode: 1},
+			"minion3": {Return: "The minion function caused an exception: Traceback", ReturnCode: 1},
+		}, nil),
+	}
+
+	assert.Equal(t, []string{"minion2"}, job.Unreachable())
+	assert.Equal(t, []string{"minion3"}, job.WithOutcome(OutcomeException))
+	assert.Equal(t, []string{"minion1"}, job.Succeeded())
+
+	wait := newJobWaitResult(job, nil)
+
+	assert.Equal(t, []string{"minion1", "minion2", "minion3"}, wait.Returned)
+	assert.Equal(t, []string{"minion2", "minion3"}, wait.Failed)
+	assert.Equal(t, []string{"minion2"}, wait.Unreachable)
+	assert.Equal(t, []string{"minion4"}, wait.Missing)
+}
@@ -0,0 +1,298 @@
+package cherrypy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MinionReturn contains the return of a function executed on a minion
+type MinionReturn struct {
+	JID        string
+	Return     interface{}
+	ReturnCode int
+	Success    bool
+
+	// Outcome tells failed functions apart from exceptions and minions which did not return
+	Outcome Outcome
+}
+
+// LocalResult contains returns of a command run with the local client per minion
+type LocalResult map[string]MinionReturn
+
+// MasterResult contains the result of a command run with the runner or wheel client
+type MasterResult struct {
+	JID       string
+	Tag       string
+	Function  string
+	User      string
+	Success   bool
+	Return    interface{}
+	Timestamp time.Time
+}
+
+// AsyncMasterJobResult contains details of a job started with the runner_async or wheel_async client
+type AsyncMasterJobResult struct {
+	ID  string `json:"jid"`
+	Tag string `json:"tag"`
+}
+
+// ReturnTag returns the tag of the event fired on the event bus when the job completes
+func (r AsyncMasterJobResult) ReturnTag() string {
+	return r.Tag + "/ret"
+}
+
+type localReturn struct {
+	JID        string      `json:"jid"`
+	Return     interface{} `json:"ret"`
+	ReturnCode int         `json:"retcode"`
+	Success    *bool       `json:"success"`
+}
+
+type masterReturn struct {
+	JID      string      `json:"jid"`
+	Tag      string      `json:"tag"`
+	Function string      `json:"fun"`
+	User     string      `json:"user"`
+	Success  *bool       `json:"success"`
+	Return   interface{} `json:"return"`
+	Stamp    string      `json:"_stamp"`
+}
+
+// Decode stores the return value in the value pointed to by v (e.g.: a struct with json tags)
+func (r MinionReturn) Decode(v interface{}) error {
+	return decodeValue(r.Return, v)
+}
+
+// Decode stores the return value in the value pointed to by v (e.g.: a struct with json tags)
+func (r MasterResult) Decode(v interface{}) error {
+	return decodeValue(r.Return, v)
+}
+
+/*
+ParseLocalResult converts a result of RunCommand() sent with the local client
+
+Both full returns (ret, retcode, success and jid) and plain returns are supported.
+*/
+func ParseLocalResult(raw interface{}) (LocalResult, error) {
+	minions, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected local client result: %T", raw)
+	}
+
+	res := make(LocalResult, len(minions))
+	for id, v := range minions {
+		r, err := parseMinionReturn(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+
+		res[id] = *r
+	}
+
+	return res, nil
+}
+
+/*
+ParseMasterResult converts a result of RunCommand() sent with the runner or wheel client
+
+The tag/data envelope returned by the wheel client is removed.
+*/
+func ParseMasterResult(raw interface{}) (*MasterResult, error) {
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected master result: %T", raw)
+	}
+
+	tag, _ := m["tag"].(string)
+	if data, ok := m["data"].(map[string]interface{}); ok && tag != "" {
+		m = data
+	}
+
+	var r masterReturn
+	if err := decodeValue(m, &r); err != nil {
+		return nil, err
+	}
+
+	res := MasterResult{
+		JID:      r.JID,
+		Tag:      r.Tag,
+		Function: r.Function,
+		User:     r.User,
+		Success:  r.Success == nil || *r.Success,
+		Return:   r.Return,
+	}
+
+	if res.Tag == "" {
+		res.Tag = tag
+	}
+
+	if t, err := time.Parse(eventTimeLayout, r.Stamp); err == nil {
+		res.Timestamp = t
+	}
+
+	return &res, nil
+}
+
+/*
+RunLocalCommand runs a command on minions using the local client and returns results per minion
+
+https://docs.saltstack.com/en/latest/ref/netapi/all/salt.netapi.rest_cherrypy.html#salt.netapi.rest_cherrypy.app.Run
+*/
+func (c *Client) RunLocalCommand(ctx context.Context, cmd Command) (LocalResult, error) {
+	cmd.Client = LocalClient
+	res, err := c.RunCommand(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseLocalResult(res)
+}
+
+/*
+RunRunnerCommand runs a runner module on the master using the runner client
+
+https://docs.saltstack.com/en/latest/ref/netapi/all/salt.netapi.rest_cherrypy.html#salt.netapi.rest_cherrypy.app.Run
+*/
+func (c *Client) RunRunnerCommand(ctx context.Context, cmd Command) (*MasterResult, error) {
+	cmd.Client = RunnerClient
+	res, err := c.RunCommand(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseMasterResult(res)
+}
+
+/*
+RunWheelCommand runs a wheel module on the master using the wheel client
+
+https://docs.saltstack.com/en/latest/ref/netapi/all/salt.netapi.rest_cherrypy.html#salt.netapi.rest_cherrypy.app.Run
+*/
+func (c *Client) RunWheelCommand(ctx context.Context, cmd Command) (*MasterResult, error) {
+	cmd.Client = WheelClient
+	res, err := c.RunCommand(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseMasterResult(res)
+}
+
+func parseMinionReturn(raw interface{}) (*MinionReturn, error) {
+	if m, ok := raw.(map[string]interface{}); ok && isFullReturn(m) {
+		var r localReturn
+		if err := decodeValue(m, &r); err != nil {
+			return nil, err
+		}
+
+		res := newMinionReturn(r.JID, r.Return, r.ReturnCode, (r.Success == nil && r.ReturnCode == 0) || (r.Success != nil && *r.Success))
+		return &res, nil
+	}
+
+	res := newMinionReturn("", raw, 0, true)
+	return &res, nil
+}
+
+// isFullReturn reports whether a minion return was sent with full_return
+func isFullReturn(m map[string]interface{}) bool {
+	_, ret := m["ret"]
+	_, retcode := m["retcode"]
+	_, jid := m["jid"]
+
+	return ret && (retcode || jid)
+}
+
+func decodeValue(src interface{}, dst interface{}) error {
+	b, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, dst)
+}
+
+/*
+ParseLocalAsyncResult converts a result of RunCommand() sent with the local_async client
+
+ID of the result is empty if no minions were targeted.
+*/
+func ParseLocalAsyncResult(raw interface{}) (*AsyncMinionJobResult, error) {
+	var res AsyncMinionJobResult
+	if err := decodeAsyncResult(raw, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// ParseMasterAsyncResult converts a result of RunCommand() sent with the runner_async or wheel_async client
+func ParseMasterAsyncResult(raw interface{}) (*AsyncMasterJobResult, error) {
+	var res AsyncMasterJobResult
+	if err := decodeAsyncResult(raw, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+/*
+RunLocalAsyncCommand sends a command to minions using the local_async client without waiting for returns
+
+Returns can be retrieved with Job() or waited for with WaitForJob() using the ID of the result.
+
+https://docs.saltstack.com/en/latest/ref/netapi/all/salt.netapi.rest_cherrypy.html#salt.netapi.rest_cherrypy.app.Run
+*/
+func (c *Client) RunLocalAsyncCommand(ctx context.Context, cmd Command) (*AsyncMinionJobResult, error) {
+	cmd.Client = LocalAsyncClient
+	res, err := c.RunCommand(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseLocalAsyncResult(res)
+}
+
+/*
+RunRunnerAsyncCommand starts a runner module on the master using the runner_async client without waiting for it to complete
+
+Result can be retrieved with Job() or waited for with WaitForJob() using the ID of the result,
+or received from event bus with ReturnTag().
+
+https://docs.saltstack.com/en/latest/ref/netapi/all/salt.netapi.rest_cherrypy.html#salt.netapi.rest_cherrypy.app.Run
+*/
+func (c *Client) RunRunnerAsyncCommand(ctx context.Context, cmd Command) (*AsyncMasterJobResult, error) {
+	cmd.Client = RunnerAsyncClient
+	res, err := c.RunCommand(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseMasterAsyncResult(res)
+}
+
+/*
+RunWheelAsyncCommand starts a wheel module on the master using the wheel_async client without waiting for it to complete
+
+Result can be received from event bus with ReturnTag().
+
+https://docs.saltstack.com/en/latest/ref/netapi/all/salt.netapi.rest_cherrypy.html#salt.netapi.rest_cherrypy.app.Run
+*/
+func (c *Client) RunWheelAsyncCommand(ctx context.Context, cmd Command) (*AsyncMasterJobResult, error) {
+	cmd.Client = WheelAsyncClient
+	res, err := c.RunCommand(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseMasterAsyncResult(res)
+}
+
+func decodeAsyncResult(raw interface{}, v interface{}) error {
+	if _, ok := raw.(map[string]interface{}); !ok {
+		return fmt.Errorf("unexpected async result: %T", raw)
+	}
+
+	return decodeValue(raw, v)
+}
@@ -0,0 +1,172 @@
+package cherrypy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunLocalCommandResult(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "local_success")
+
+	res, err := c.RunLocalCommand(context.Background(), Command{
+		Target:   ExpressionTarget{Expression: "minion1", Type: Glob},
+		Function: "test.ping",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(res))
+	assert.Equal(t, "20200205193702331160", res["minion1"].JID)
+	assert.Equal(t, true, res["minion1"].Return)
+	assert.Equal(t, 0, res["minion1"].ReturnCode)
+	assert.True(t, res["minion1"].Success)
+}
+
+func TestRunLocalCommandFailureResult(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "local_failure")
+
+	res, err := c.RunLocalCommand(context.Background(), Command{
+		Target:    ExpressionTarget{Expression: "minion1", Type: Glob},
+		Function:  "cmd.run",
+		Arguments: map[string]interface{}{"arg": []interface{}{"exit 1"}},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res["minion1"].ReturnCode)
+	assert.False(t, res["minion1"].Success)
+}
+
+func TestRunRunnerCommandResult(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "runner_success")
+
+	res, err := c.RunRunnerCommand(context.Background(), Command{
+		Function: "manage.up",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "runner.manage.up", res.Function)
+	assert.Equal(t, "20200205201532185766", res.JID)
+	assert.Equal(t, testUsername, res.User)
+	assert.True(t, res.Success)
+	assert.Equal(t, time.Date(2020, time.February, 5, 20, 15, 32, 704143000, time.UTC), res.Timestamp)
+
+	var up []string
+	assert.NoError(t, res.Decode(&up))
+	assert.Equal(t, []string{"minion1"}, up)
+}
+
+func TestRunWheelCommandResult(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "wheel_success")
+
+	res, err := c.RunWheelCommand(context.Background(), Command{
+		Function: "minions.connected",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "wheel.minions.connected", res.Function)
+	assert.Equal(t, "salt/wheel/20200202224725441938", res.Tag)
+	assert.Equal(t, "20200202224725441938", res.JID)
+	assert.True(t, res.Success)
+
+	var connected []string
+	assert.NoError(t, res.Decode(&connected))
+	assert.Equal(t, []string{"minion1"}, connected)
+}
+
+func TestParseLocalResultPlainReturn(t *testing.T) {
+	res, err := ParseLocalResult(map[string]interface{}{
+		"minion1": map[string]interface{}{"os": "Ubuntu"},
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, res["minion1"].Success)
+
+	var grains struct {
+		OS string `json:"os"`
+	}
+	assert.NoError(t, res["minion1"].Decode(&grains))
+	assert.Equal(t, "Ubuntu", grains.OS)
+
+	_, err = ParseLocalResult("unexpected")
+	assert.Error(t, err)
+}
+
+func TestRunLocalAsyncCommandResult(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "local_async_success")
+
+	res, err := c.RunLocalAsyncCommand(context.Background(), Command{
+		Target:   ExpressionTarget{Expression: "*", Type: Glob},
+		Function: "test.ping",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "20200205202406877932", res.ID)
+	assert.Equal(t, []string{"minion1", "minion2"}, res.Minions)
+}
+
+func TestRunRunnerAsyncCommandResult(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "runner_async_success")
+
+	res, err := c.RunRunnerAsyncCommand(context.Background(), Command{
+		Function:            "state.orchestrate",
+		PositionalArguments: []interface{}{"orch.deploy"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "20200205202513331428", res.ID)
+	assert.Equal(t, "salt/run/20200205202513331428", res.Tag)
+	assert.Equal(t, "salt/run/20200205202513331428/ret", res.ReturnTag())
+}
+
+func TestWaitForRunnerAsyncCommand(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "runner_async_success")
+	setupSequence(t, tester, "jobs_get", "runner_published", "runner_completed")
+
+	job, err := c.RunRunnerAsyncCommand(context.Background(), Command{
+		Function:            "state.orchestrate",
+		PositionalArguments: []interface{}{"orch.deploy"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.WaitForJob(context.Background(), job.ID, WaitOptions{
+		Interval: time.Millisecond,
+		Timeout:  time.Second,
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, res.Complete())
+	assert.Equal(t, []interface{}{"minion1", "minion2"}, res.Job.Results["master_master"].Return)
+}
+
+func TestRunWheelAsyncCommandResult(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "wheel_async_success")
+
+	res, err := c.RunWheelAsyncCommand(context.Background(), Command{
+		Function:    "key.accept",
+		KWArguments: map[string]interface{}{"match": "minion3"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "20200205202611583022", res.ID)
+	assert.Equal(t, "salt/wheel/20200205202611583022", res.Tag)
+}
@@ -2,7 +2,9 @@ package cherrypy
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -64,7 +66,110 @@ func TestRunWheelCommand(t *testing.T) {
 	assert.NotNil(t, res)
 }
 
-// TODO: Add runner test
-// TODO: Add test with arguments
-// TODO: Add test with kw arguments
+func TestRunLocalCommandWithArguments(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "local_arguments")
+
+	cmd := Command{
+		Client:              LocalClient,
+		Target:              ExpressionTarget{Expression: "minion1", Type: Glob},
+		Function:            "cmd.run",
+		PositionalArguments: []interface{}{"echo $GREETING"},
+		KWArguments: map[string]interface{}{
+			"env":          map[string]interface{}{"GREETING": "Hello"},
+			"python_shell": true,
+		},
+		Timeout:  30 * time.Second,
+		Returner: "syslog",
+	}
+
+	res, err := c.RunCommand(context.Background(), cmd)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, res)
+}
+
+func TestRunWheelCommandWithArguments(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "wheel_arguments")
+
+	cmd := Command{
+		Client:      WheelClient,
+		Function:    "key.name_match",
+		KWArguments: map[string]interface{}{"match": "minion1"},
+		Timeout:     30 * time.Second,
+	}
+
+	res, err := c.RunCommand(context.Background(), cmd)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, res)
+}
+
+func TestRunnerLowstate(t *testing.T) {
+	c := NewClient("http://master:8000", testUsername, testPassword, testEAuth, false)
+
+	d, err := c.lowstate(Command{
+		Client:              RunnerClient,
+		Function:            "jobs.list_jobs",
+		PositionalArguments: []interface{}{},
+		KWArguments:         map[string]interface{}{"search_function": "test.ping"},
+		Timeout:             1500 * time.Millisecond,
+		Returner:            "syslog",
+		Arguments:           map[string]interface{}{"print_event": false},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"client":      CommandClient(RunnerClient),
+		"fun":         "jobs.list_jobs",
+		"username":    testUsername,
+		"password":    testPassword,
+		"eauth":       testEAuth,
+		"arg":         []interface{}{},
+		"kwarg":       map[string]interface{}{"search_function": "test.ping"},
+		"timeout":     2,
+		"print_event": false,
+		"full_return": true,
+	}, d)
+}
+
+func TestLowstateFullReturnConflict(t *testing.T) {
+	c := NewClient("http://master:8000", testUsername, testPassword, testEAuth, false)
+
+	for _, client := range []CommandClient{WheelClient, WheelAsyncClient, LocalAsyncClient, RunnerAsyncClient, SSHClient, LocalBatchClient} {
+		_, err := c.lowstate(Command{
+			Client:    client,
+			Function:  "test.ping",
+			Arguments: map[string]interface{}{"full_return": true},
+		})
+
+		assert.True(t, errors.Is(err, ErrorFullReturnConflict), client)
+	}
+
+	_, err := c.lowstate(Command{
+		Client:    LocalClient,
+		Function:  "test.ping",
+		Arguments: map[string]interface{}{"full_return": false},
+	})
+	assert.True(t, errors.Is(err, ErrorFullReturnConflict))
+
+	d, err := c.lowstate(Command{
+		Client:    LocalClient,
+		Function:  "test.ping",
+		Arguments: map[string]interface{}{"full_return": true},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, true, d["full_return"])
+
+	_, err = c.RunCommand(context.Background(), Command{
+		Client:    WheelClient,
+		Function:  "key.list_all",
+		Arguments: map[string]interface{}{"full_return": true},
+	})
+	assert.True(t, errors.Is(err, ErrorFullReturnConflict))
+}
+
 // TODO: Add tests with 401
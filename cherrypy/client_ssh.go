@@ -0,0 +1,228 @@
+package cherrypy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// SSHClient sends commands to hosts over SSH without a minion. Equivalent to the salt-ssh CLI command.
+const SSHClient CommandClient = "ssh"
+
+/*
+SSHOptions configures how salt-ssh connects to the targeted hosts
+
+Hosts are targeted with Target of the command and matched against the roster.
+
+https://docs.saltstack.com/en/latest/topics/ssh/index.html
+*/
+type SSHOptions struct {
+	// Roster is the roster system used to find hosts (e.g.: flat, scan, ansible)
+	Roster string
+
+	// RosterFile is the path of the roster file on the master
+	RosterFile string
+
+	// PrivateKey is the path of the SSH private key on the master (ssh_priv)
+	PrivateKey string
+
+	// User overrides the user in the roster (ssh_user)
+	User string
+
+	// Password overrides the password in the roster (ssh_passwd)
+	Password string
+
+	// Port overrides the port in the roster (ssh_port)
+	Port int
+
+	// Sudo runs the command with sudo (ssh_sudo)
+	Sudo bool
+
+	// SudoUser runs the command as the user with sudo (ssh_sudo_user)
+	SudoUser string
+
+	// IgnoreHostKeys disables host key verification
+	IgnoreHostKeys bool
+
+	// IdentitiesOnly uses only the private key provided (ssh_identities_only)
+	IdentitiesOnly bool
+
+	// RawShell runs the function as a raw shell command instead of a Salt module (raw_shell)
+	RawShell bool
+
+	// ConnectTimeout limits the time to establish the connection (ssh_timeout). Sent in seconds.
+	ConnectTimeout time.Duration
+}
+
+// SSHHostResult contains the return of a command executed on a host with salt-ssh
+type SSHHostResult struct {
+	ID         string
+	JID        string
+	Function   string
+	Return     interface{}
+	ReturnCode int
+	Stdout     string
+	Stderr     string
+	Success    bool
+}
+
+// SSHResult contains returns of a command run with the ssh client per host
+type SSHResult map[string]SSHHostResult
+
+type sshReturn struct {
+	ID         string       `json:"id"`
+	JID        string       `json:"jid"`
+	Function   string       `json:"fun"`
+	Return     *interface{} `json:"return"`
+	ReturnCode *int         `json:"retcode"`
+	Stdout     string       `json:"stdout"`
+	Stderr     string       `json:"stderr"`
+}
+
+// Decode stores the return value in the value pointed to by v (e.g.: a struct with json tags)
+func (r SSHHostResult) Decode(v interface{}) error {
+	return decodeValue(r.Return, v)
+}
+
+func (o *SSHOptions) lowstate(d map[string]interface{}) {
+	if o.Roster != "" {
+		d["roster"] = o.Roster
+	}
+
+	if o.RosterFile != "" {
+		d["roster_file"] = o.RosterFile
+	}
+
+	if o.PrivateKey != "" {
+		d["ssh_priv"] = o.PrivateKey
+	}
+
+	if o.User != "" {
+		d["ssh_user"] = o.User
+	}
+
+	if o.Password != "" {
+		d["ssh_passwd"] = o.Password
+	}
+
+	if o.Port > 0 {
+		d["ssh_port"] = o.Port
+	}
+
+	if o.Sudo {
+		d["ssh_sudo"] = true
+	}
+
+	if o.SudoUser != "" {
+		d["ssh_sudo_user"] = o.SudoUser
+	}
+
+	if o.IgnoreHostKeys {
+		d["ignore_host_keys"] = true
+	}
+
+	if o.IdentitiesOnly {
+		d["ssh_identities_only"] = true
+	}
+
+	if o.RawShell {
+		d["raw_shell"] = true
+	}
+
+	if o.ConnectTimeout > 0 {
+		d["ssh_timeout"] = int(math.Ceil(o.ConnectTimeout.Seconds()))
+	}
+}
+
+/*
+ParseSSHResult converts a result of RunCommand() sent with the ssh client
+
+Returns of Salt functions (return and retcode) and raw shell outputs (stdout, stderr and retcode) are supported.
+Hosts which could not be reached are reported with the error of ssh in Stderr.
+*/
+func ParseSSHResult(raw interface{}) (SSHResult, error) {
+	hosts, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected ssh client result: %T", raw)
+	}
+
+	res := make(SSHResult, len(hosts))
+	for id, v := range hosts {
+		r, err := parseSSHHostResult(id, v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+
+		res[id] = *r
+	}
+
+	return res, nil
+}
+
+/*
+RunSSHCommand runs a command on hosts using the ssh client and returns results per host
+
+https://docs.saltstack.com/en/latest/ref/netapi/all/salt.netapi.rest_cherrypy.html#salt.netapi.rest_cherrypy.app.Run
+*/
+func (c *Client) RunSSHCommand(ctx context.Context, cmd Command) (SSHResult, error) {
+	cmd.Client = SSHClient
+	res, err := c.RunCommand(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseSSHResult(res)
+}
+
+func parseSSHHostResult(id string, raw interface{}) (*SSHHostResult, error) {
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		// Returned as is when the output of the host could not be parsed
+		return &SSHHostResult{
+			ID:      id,
+			Return:  raw,
+			Success: true,
+		}, nil
+	}
+
+	_, hasReturn := m["return"]
+	_, hasRetcode := m["retcode"]
+	_, hasStdout := m["stdout"]
+	_, hasStderr := m["stderr"]
+	if !hasReturn && !hasRetcode && !hasStdout && !hasStderr {
+		return &SSHHostResult{
+			ID:      id,
+			Return:  raw,
+			Success: true,
+		}, nil
+	}
+
+	var r sshReturn
+	if err := decodeValue(m, &r); err != nil {
+		return nil, err
+	}
+
+	res := SSHHostResult{
+		ID:       r.ID,
+		JID:      r.JID,
+		Function: r.Function,
+		Stdout:   r.Stdout,
+		Stderr:   r.Stderr,
+	}
+
+	if res.ID == "" {
+		res.ID = id
+	}
+
+	if r.Return != nil {
+		res.Return = *r.Return
+	}
+
+	if r.ReturnCode != nil {
+		res.ReturnCode = *r.ReturnCode
+	}
+
+	res.Success = res.ReturnCode == 0
+	return &res, nil
+}
@@ -0,0 +1,104 @@
+package cherrypy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunSSHCommand(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "ssh_success")
+
+	res, err := c.RunSSHCommand(context.Background(), Command{
+		Target:              ExpressionTarget{Expression: "web*", Type: Glob},
+		Function:            "cmd.run",
+		PositionalArguments: []interface{}{"uptime"},
+		Timeout:             time.Minute,
+		SSH: &SSHOptions{
+			Roster:         "flat",
+			RosterFile:     "/etc/salt/roster",
+			PrivateKey:     "/etc/salt/pki/master/ssh/salt-ssh.rsa",
+			User:           "deploy",
+			Sudo:           true,
+			IgnoreHostKeys: true,
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+
+	web1 := res["web1"]
+	assert.Equal(t, "web1", web1.ID)
+	assert.Equal(t, "20200205203015214785", web1.JID)
+	assert.Equal(t, "cmd.run", web1.Function)
+	assert.Contains(t, web1.Return, "load average")
+	assert.Equal(t, 0, web1.ReturnCode)
+	assert.True(t, web1.Success)
+
+	web2 := res["web2"]
+	assert.Equal(t, "web2", web2.ID)
+	assert.Nil(t, web2.Return)
+	assert.Equal(t, 255, web2.ReturnCode)
+	assert.Contains(t, web2.Stderr, "Connection refused")
+	assert.False(t, web2.Success)
+}
+
+func TestRunSSHRawShell(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "ssh_raw_shell")
+
+	res, err := c.RunSSHCommand(context.Background(), Command{
+		Target:   ExpressionTarget{Expression: "web1", Type: Glob},
+		Function: "ls /missing",
+		SSH:      &SSHOptions{RawShell: true},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res["web1"].ReturnCode)
+	assert.Equal(t, "", res["web1"].Stdout)
+	assert.Contains(t, res["web1"].Stderr, "No such file or directory")
+	assert.False(t, res["web1"].Success)
+}
+
+func TestParseSSHResultPlainReturn(t *testing.T) {
+	res, err := ParseSSHResult(map[string]interface{}{
+		"web1": "Minion did not return",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Minion did not return", res["web1"].Return)
+	assert.Equal(t, "web1", res["web1"].ID)
+
+	_, err = ParseSSHResult([]interface{}{})
+	assert.Error(t, err)
+}
+
+func TestSSHLowstate(t *testing.T) {
+	c := NewClient("http://localhost", "test_user", "test_pwd", "pam", false)
+
+	d, err := c.lowstate(Command{
+		Client:   SSHClient,
+		Target:   ExpressionTarget{Expression: "web1", Type: Glob},
+		Function: "test.ping",
+		SSH: &SSHOptions{
+			Password:       "secret",
+			Port:           2222,
+			SudoUser:       "salt",
+			IdentitiesOnly: true,
+			ConnectTimeout: 1500 * time.Millisecond,
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", d["ssh_passwd"])
+	assert.Equal(t, 2222, d["ssh_port"])
+	assert.Equal(t, "salt", d["ssh_sudo_user"])
+	assert.Equal(t, true, d["ssh_identities_only"])
+	assert.Equal(t, 2, d["ssh_timeout"])
+	assert.NotContains(t, d, "full_return")
+}
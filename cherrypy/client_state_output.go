@@ -0,0 +1,306 @@
+package cherrypy
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HighstateOutputMode selects how states are printed like the state_output option of Salt
+type HighstateOutputMode int
+
+const (
+	// HighstateOutputFull prints all details of each state
+	HighstateOutputFull HighstateOutputMode = iota
+	// HighstateOutputTerse prints a single line per state
+	HighstateOutputTerse
+	// HighstateOutputMixed prints a single line per state unless it failed
+	HighstateOutputMixed
+	// HighstateOutputChanges prints a single line per state unless it failed or made changes
+	HighstateOutputChanges
+)
+
+// HighstateOutputOptions control how WriteHighstate() formats results
+type HighstateOutputOptions struct {
+	Mode HighstateOutputMode
+
+	// Color enables ANSI colors
+	Color bool
+
+	// HideUnchanged skips successful states without changes like state_verbose=False of Salt
+	HideUnchanged bool
+}
+
+type outputColors struct {
+	green, red, lightRed, cyan, lightYellow, yellow, endc string
+}
+
+var ansiColors = outputColors{
+	green:       "\033[0;32m",
+	red:         "\033[0;31m",
+	lightRed:    "\033[1;31m",
+	cyan:        "\033[0;36m",
+	lightYellow: "\033[1;33m",
+	yellow:      "\033[0;33m",
+	endc:        "\033[0m",
+}
+
+/*
+WriteHighstate writes results of states in the format of Salt's highstate outputter
+
+Minions are written in sorted order, each followed by its summary.
+
+https://docs.saltstack.com/en/latest/ref/output/all/salt.output.highstate.html
+*/
+func WriteHighstate(w io.Writer, res HighstateResult, opts HighstateOutputOptions) error {
+	ids := make([]string, 0, len(res))
+	for id := range res {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+	for _, id := range ids {
+		if _, err := io.WriteString(w, formatHost(id, res[id], opts)+"\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func formatHost(host string, m *MinionStates, opts HighstateOutputOptions) string {
+	var c outputColors
+	if opts.Color {
+		c = ansiColors
+	}
+
+	if m == nil {
+		m = &MinionStates{}
+	}
+
+	var lines []string
+	hcolor := c.green
+
+	if len(m.Errors) > 0 {
+		hcolor = c.lightRed
+		lines = append(lines, fmt.Sprintf("    %sData failed to compile:%s", hcolor, c.endc))
+		for _, err := range m.Errors {
+			lines = append(lines, fmt.Sprintf("%s----------\n    %s%s", hcolor, err, c.endc))
+		}
+
+		return strings.Join(append([]string{fmt.Sprintf("%s%s:%s", hcolor, host, c.endc)}, lines...), "\n")
+	}
+
+	for _, s := range m.States {
+		changed := s.Changed()
+		if opts.HideUnchanged && s.Result != nil && *s.Result && !changed {
+			continue
+		}
+
+		tcolor := c.green
+		if changed {
+			tcolor = c.cyan
+		}
+
+		if s.Result == nil {
+			hcolor, tcolor = c.lightYellow, c.lightYellow
+		} else if !*s.Result {
+			hcolor, tcolor = c.red, c.red
+		}
+
+		switch {
+		case opts.Mode == HighstateOutputTerse,
+			opts.Mode == HighstateOutputMixed && s.Succeeded(),
+			opts.Mode == HighstateOutputChanges && s.Result != nil && *s.Result && !changed:
+			lines = append(lines, formatTerse(s, tcolor, c))
+		default:
+			lines = append(lines, formatState(s, tcolor, c)...)
+		}
+	}
+
+	lines = append(lines, formatSummary(host, m.Summary(), c)...)
+	return strings.Join(append([]string{fmt.Sprintf("%s%s:%s", hcolor, host, c.endc)}, lines...), "\n")
+}
+
+func formatState(s StateResult, tcolor string, c outputColors) []string {
+	comment := strings.Replace(strings.TrimSpace(s.Comment), "\n", "\n"+strings.Repeat(" ", 14), -1)
+
+	lines := []string{
+		fmt.Sprintf("%s----------%s", tcolor, c.endc),
+		fmt.Sprintf("    %s      ID: %s%s", tcolor, s.ID, c.endc),
+		fmt.Sprintf("    %sFunction: %s%s", tcolor, s.Function, c.endc),
+	}
+
+	if s.ID != s.Name {
+		lines = append(lines, fmt.Sprintf("    %s    Name: %s%s", tcolor, s.Name, c.endc))
+	}
+
+	lines = append(lines,
+		fmt.Sprintf("    %s  Result: %s%s", tcolor, formatStateResult(s.Result), c.endc),
+		fmt.Sprintf("    %s Comment: %s%s", tcolor, comment, c.endc),
+	)
+
+	if s.StartTime != "" {
+		lines = append(lines,
+			fmt.Sprintf("    %s Started: %s%s", tcolor, s.StartTime, c.endc),
+			fmt.Sprintf("    %sDuration: %s ms%s", tcolor, formatMilliseconds(s), c.endc),
+		)
+	}
+
+	changes := "     Changes:   "
+	if s.Changed() {
+		changes += "\n" + strings.Join(formatNested(s.Changes, 14, "", c, nil), "\n")
+	}
+
+	return append(lines, fmt.Sprintf("%s%s%s", tcolor, changes, c.endc))
+}
+
+func formatTerse(s StateResult, tcolor string, c outputColors) string {
+	result := "Clean"
+	switch {
+	case s.Result == nil:
+		result = "Differs"
+	case !*s.Result:
+		result = "Failed"
+	case s.Changed():
+		result = "Changed"
+	}
+
+	msg := fmt.Sprintf("%s Name: %s - Function: %s - Result: %s", tcolor, s.Name, s.Function, result)
+	if s.StartTime != "" {
+		msg += fmt.Sprintf(" Started: - %s Duration: %s ms", s.StartTime, formatMilliseconds(s))
+	}
+
+	return msg + c.endc
+}
+
+func formatSummary(host string, sum StateSummary, c outputColors) []string {
+	notRun := sum.Unchanged
+	succeeded := sum.Succeeded - notRun
+
+	countLen := 0
+	for _, n := range []int{succeeded, sum.Failed, notRun} {
+		if n > 0 && len(strconv.Itoa(n)) > countLen {
+			countLen = len(strconv.Itoa(n))
+		}
+	}
+
+	// Longest label of Salt's outputter is "Succeeded"; 2 for ": "
+	lineLen := len("Succeeded") + countLen + 2
+	counts := func(label string, n int) string {
+		return fmt.Sprintf("%s: %*d", label, lineLen-len(label)-2, n)
+	}
+
+	var stats []string
+	if notRun > 0 {
+		stats = append(stats, fmt.Sprintf("%sunchanged=%d%s", c.lightYellow, notRun, c.endc))
+	}
+
+	if sum.Changed > 0 {
+		stats = append(stats, fmt.Sprintf("%schanged=%d%s", c.green, sum.Changed, c.endc))
+	}
+
+	changeStats := ""
+	if len(stats) > 0 {
+		changeStats = " (" + strings.Join(stats, ", ") + ")"
+	}
+
+	failedColor := c.cyan
+	if sum.Failed > 0 {
+		failedColor = c.red
+	}
+
+	duration := float64(sum.Duration.Nanoseconds()) / 1e6
+	unit := "ms"
+	if duration > 999 {
+		duration /= 1000
+		unit = "s"
+	}
+
+	separator := strings.Repeat("-", lineLen)
+	return []string{
+		fmt.Sprintf("%s\nSummary for %s\n%s%s", c.cyan, host, separator, c.endc),
+		fmt.Sprintf("%s%s%s%s", c.green, counts("Succeeded", sum.Succeeded), c.endc, changeStats),
+		fmt.Sprintf("%s%s%s", failedColor, counts("Failed", sum.Failed), c.endc),
+		fmt.Sprintf("%s%s\nTotal states run: %*d%s", c.cyan, separator, lineLen-7, sum.Total, c.endc),
+		fmt.Sprintf("%sTotal run time: %*s %s%s", c.cyan, lineLen-5, strconv.FormatFloat(duration, 'f', 3, 64), unit, c.endc),
+	}
+}
+
+// formatNested formats the value like Salt's nested outputter
+func formatNested(v interface{}, indent int, prefix string, c outputColors, out []string) []string {
+	pad := strings.Repeat(" ", indent)
+	switch val := v.(type) {
+	case nil:
+		return append(out, pad+c.lightRed+prefix+"None"+c.endc)
+	case bool:
+		return append(out, pad+c.green+prefix+formatBool(val)+c.endc)
+	case float64:
+		return append(out, pad+c.yellow+prefix+strconv.FormatFloat(val, 'f', -1, 64)+c.endc)
+	case string:
+		linePrefix := prefix
+		for _, line := range strings.Split(strings.TrimRight(val, "\n"), "\n") {
+			out = append(out, pad+c.green+linePrefix+line+c.endc)
+			linePrefix = strings.Repeat(" ", len(prefix))
+		}
+
+		return out
+	case []interface{}:
+		for _, item := range val {
+			switch item.(type) {
+			case map[string]interface{}:
+				out = append(out, pad+c.green+"|_"+c.endc)
+				out = formatNested(item, indent+2, "", c, out)
+			case []interface{}:
+				out = append(out, pad+c.green+"|_"+c.endc)
+				out = formatNested(item, indent+2, "- ", c, out)
+			default:
+				out = formatNested(item, indent, "- ", c, out)
+			}
+		}
+
+		return out
+	case map[string]interface{}:
+		if indent > 0 {
+			out = append(out, pad+c.cyan+prefix+"----------"+c.endc)
+		}
+
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+		for _, k := range keys {
+			out = append(out, pad+c.cyan+prefix+k+c.endc+":")
+			out = formatNested(val[k], indent+4, "", c, out)
+		}
+
+		return out
+	default:
+		return append(out, pad+c.green+prefix+fmt.Sprint(val)+c.endc)
+	}
+}
+
+func formatStateResult(result *bool) string {
+	if result == nil {
+		return "None"
+	}
+
+	return formatBool(*result)
+}
+
+// formatBool formats the value like Python
+func formatBool(v bool) string {
+	if v {
+		return "True"
+	}
+
+	return "False"
+}
+
+func formatMilliseconds(s StateResult) string {
+	return strconv.FormatFloat(float64(s.Duration.Nanoseconds())/1e6, 'f', -1, 64)
+}
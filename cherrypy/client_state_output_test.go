@@ -0,0 +1,124 @@
+package cherrypy
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupHighstate(t *testing.T) HighstateResult {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "local_highstate")
+
+	raw, err := c.RunCommand(context.Background(), Command{
+		Client:              LocalClient,
+		Target:              ExpressionTarget{Expression: "minion*", Type: Glob},
+		Function:            "state.apply",
+		PositionalArguments: []interface{}{"motd"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := ParseHighstate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func renderHighstate(t *testing.T, res HighstateResult, opts HighstateOutputOptions) string {
+	var buf bytes.Buffer
+	if err := WriteHighstate(&buf, res, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.String()
+}
+
+func TestWriteHighstateFull(t *testing.T) {
+	res := setupHighstate(t)
+	expected, err := ioutil.ReadFile("testdata/highstate_full.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, string(expected), renderHighstate(t, res, HighstateOutputOptions{}))
+}
+
+func TestWriteHighstateTerse(t *testing.T) {
+	res := setupHighstate(t)
+	expected, err := ioutil.ReadFile("testdata/highstate_terse.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, string(expected), renderHighstate(t, res, HighstateOutputOptions{Mode: HighstateOutputTerse}))
+}
+
+func TestWriteHighstateMixed(t *testing.T) {
+	res := setupHighstate(t)
+	out := renderHighstate(t, res, HighstateOutputOptions{Mode: HighstateOutputMixed})
+
+	assert.Contains(t, out, " Name: /etc/motd - Function: file.managed - Result: Changed")
+	assert.Contains(t, out, "          ID: banner\n")
+	assert.NotContains(t, out, "          ID: motd\n")
+}
+
+func TestWriteHighstateChanges(t *testing.T) {
+	res := setupHighstate(t)
+	out := renderHighstate(t, res, HighstateOutputOptions{Mode: HighstateOutputChanges})
+
+	assert.Contains(t, out, " Name: figlet - Function: pkg.installed - Result: Clean")
+	assert.Contains(t, out, "          ID: motd\n")
+	assert.Contains(t, out, "          ID: banner\n")
+}
+
+func TestWriteHighstateHideUnchanged(t *testing.T) {
+	res := setupHighstate(t)
+	out := renderHighstate(t, res, HighstateOutputOptions{HideUnchanged: true})
+
+	assert.NotContains(t, out, "ID: figlet")
+	assert.Contains(t, out, "ID: motd")
+	assert.Contains(t, out, "Total states run:     3")
+}
+
+func TestWriteHighstateColor(t *testing.T) {
+	res := setupHighstate(t)
+	out := renderHighstate(t, res, HighstateOutputOptions{Color: true})
+
+	assert.True(t, strings.HasPrefix(out, "\033[0;31mminion1:\033[0m\n"))
+	assert.Contains(t, out, "\033[0;36m----------\033[0m\n    \033[0;36m      ID: motd\033[0m")
+	assert.Contains(t, out, "\033[0;32mSucceeded: 2\033[0m (\033[0;32mchanged=1\033[0m)")
+	assert.Contains(t, out, "\033[0;31mFailed:    1\033[0m")
+	assert.Contains(t, out, "\033[1;31mminion2:\033[0m")
+}
+
+func TestWriteHighstateTestMode(t *testing.T) {
+	states, err := ParseMinionStates(map[string]interface{}{
+		"file_|-motd_|-/etc/motd_|-managed": map[string]interface{}{
+			"changes":    map[string]interface{}{"diff": "+Welcome", "mode": []interface{}{"0644", map[string]interface{}{"new": 420.0}}},
+			"comment":    "The file /etc/motd is set to be changed",
+			"name":       "/etc/motd",
+			"result":     nil,
+			"start_time": "20:43:11.581218",
+			"duration":   1500.0,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := renderHighstate(t, HighstateResult{"minion1": states}, HighstateOutputOptions{})
+
+	assert.Contains(t, out, "      Result: None\n")
+	assert.Contains(t, out, "              mode:\n                  - 0644\n                  |_\n                    ----------\n                    new:\n                        420\n")
+	assert.Contains(t, out, "Succeeded: 1 (unchanged=1, changed=1)\n")
+	assert.Contains(t, out, "Total run time:   1.500 s\n")
+}
@@ -0,0 +1,279 @@
+package cherrypy
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stateKeySeparator separates components of a state key (e.g.: file_|-motd_|-/etc/motd_|-managed)
+const stateKeySeparator = "_|-"
+
+// StateResult contains the result of a single state executed by state.apply, state.sls or state.highstate
+type StateResult struct {
+	// Key is the key of the state in the return (module_|-id_|-name_|-function)
+	Key string
+
+	ID       string
+	Name     string
+	Module   string
+	Function string
+
+	// Result is nil if the state would have made changes in test mode
+	Result *bool
+
+	Changes   map[string]interface{}
+	Comment   string
+	StartTime string
+	Duration  time.Duration
+	RunNum    int
+	SLS       string
+}
+
+// MinionStates contains the results of states executed on a minion ordered by execution
+type MinionStates struct {
+	States []StateResult
+
+	// Errors contain messages of failures which prevented states from running (e.g.: rendering errors)
+	Errors []string
+}
+
+// HighstateResult contains results of states per minion
+type HighstateResult map[string]*MinionStates
+
+/*
+StateSummary contains counts of state results of a minion
+
+Counts match the summary printed by Salt's highstate outputter.
+*/
+type StateSummary struct {
+	// Succeeded counts states with a true result or no result (test mode) like Salt
+	Succeeded int
+
+	// Unchanged counts states with no result which Salt prints as unchanged in test mode
+	Unchanged int
+
+	Failed   int
+	Changed  int
+	Total    int
+	Duration time.Duration
+}
+
+type stateReturn struct {
+	ID        string                 `json:"__id__"`
+	Name      interface{}            `json:"name"`
+	Result    *bool                  `json:"result"`
+	Changes   map[string]interface{} `json:"changes"`
+	Comment   interface{}            `json:"comment"`
+	StartTime string                 `json:"start_time"`
+	Duration  interface{}            `json:"duration"`
+	RunNum    int                    `json:"__run_num__"`
+	SLS       string                 `json:"__sls__"`
+}
+
+// Succeeded reports whether the state did not fail
+func (s StateResult) Succeeded() bool {
+	return s.Result == nil || *s.Result
+}
+
+// Changed reports whether the state made or would make changes
+func (s StateResult) Changed() bool {
+	return len(s.Changes) > 0
+}
+
+// Summary counts results of the states
+func (m *MinionStates) Summary() StateSummary {
+	var sum StateSummary
+	for _, s := range m.States {
+		sum.Total++
+		sum.Duration += s.Duration
+
+		switch {
+		case s.Result == nil:
+			sum.Succeeded++
+			sum.Unchanged++
+		case *s.Result:
+			sum.Succeeded++
+		default:
+			sum.Failed++
+		}
+
+		if s.Changed() {
+			sum.Changed++
+		}
+	}
+
+	return sum
+}
+
+// Succeeded reports whether all states succeeded without errors
+func (m *MinionStates) Succeeded() bool {
+	return len(m.Errors) == 0 && m.Summary().Failed == 0
+}
+
+// Highstate parses returns of the job as results of states
+func (j *JobDetails) Highstate() (HighstateResult, error) {
+	return ParseHighstate(j.Returns)
+}
+
+// States parses the return as results of states
+func (r MinionReturn) States() (*MinionStates, error) {
+	return ParseMinionStates(r.Return)
+}
+
+/*
+ParseHighstate converts returns per minion of state.apply, state.sls or state.highstate
+
+Both RunCommand() results of the local client and Returns of JobDetails are supported.
+*/
+func ParseHighstate(raw interface{}) (HighstateResult, error) {
+	minions, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected highstate result: %T", raw)
+	}
+
+	res := make(HighstateResult, len(minions))
+	for id, v := range minions {
+		if m, ok := v.(map[string]interface{}); ok && isFullReturn(m) {
+			v = m["ret"]
+		}
+
+		states, err := ParseMinionStates(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+
+		res[id] = states
+	}
+
+	return res, nil
+}
+
+/*
+ParseMinionStates converts the return of a single minion of state.apply, state.sls or state.highstate
+
+States are ordered by __run_num__ like Salt's highstate outputter.
+*/
+func ParseMinionStates(raw interface{}) (*MinionStates, error) {
+	switch v := raw.(type) {
+	case []interface{}:
+		errs := make([]string, len(v))
+		for i, e := range v {
+			errs[i] = fmt.Sprint(e)
+		}
+
+		return &MinionStates{States: []StateResult{}, Errors: errs}, nil
+	case string:
+		return &MinionStates{States: []StateResult{}, Errors: []string{v}}, nil
+	case map[string]interface{}:
+		res := MinionStates{States: make([]StateResult, 0, len(v))}
+		for key, ret := range v {
+			s, err := parseStateResult(key, ret)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+
+			res.States = append(res.States, *s)
+		}
+
+		sort.SliceStable(res.States, func(i, j int) bool {
+			if res.States[i].RunNum != res.States[j].RunNum {
+				return res.States[i].RunNum < res.States[j].RunNum
+			}
+
+			return res.States[i].Key < res.States[j].Key
+		})
+
+		return &res, nil
+	default:
+		return nil, fmt.Errorf("unexpected state result: %T", raw)
+	}
+}
+
+func parseStateResult(key string, raw interface{}) (*StateResult, error) {
+	parts := strings.Split(key, stateKeySeparator)
+	if len(parts) < 4 {
+		return nil, errors.New("unexpected state key")
+	}
+
+	if _, ok := raw.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("unexpected state result: %T", raw)
+	}
+
+	var r stateReturn
+	if err := decodeValue(raw, &r); err != nil {
+		return nil, err
+	}
+
+	module := parts[0]
+	res := StateResult{
+		Key:       key,
+		ID:        parts[1],
+		Name:      strings.Join(parts[2:len(parts)-1], stateKeySeparator),
+		Module:    module,
+		Function:  module + "." + parts[len(parts)-1],
+		Result:    r.Result,
+		Changes:   r.Changes,
+		Comment:   stateComment(r.Comment),
+		StartTime: r.StartTime,
+		Duration:  stateDuration(r.Duration),
+		RunNum:    r.RunNum,
+		SLS:       r.SLS,
+	}
+
+	if r.ID != "" {
+		res.ID = r.ID
+	}
+
+	if r.Name != nil {
+		res.Name = fmt.Sprint(r.Name)
+	}
+
+	if res.Changes == nil {
+		res.Changes = map[string]interface{}{}
+	}
+
+	return &res, nil
+}
+
+// stateComment joins comments sent as a list by some states
+func stateComment(v interface{}) string {
+	switch c := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return c
+	case []interface{}:
+		lines := make([]string, len(c))
+		for i, l := range c {
+			lines[i] = fmt.Sprint(l)
+		}
+
+		return strings.Join(lines, "\n")
+	default:
+		return fmt.Sprint(c)
+	}
+}
+
+// stateDuration converts duration in milliseconds sent as a number or as a string (e.g.: "12.5 ms")
+func stateDuration(v interface{}) time.Duration {
+	var ms float64
+	switch d := v.(type) {
+	case float64:
+		ms = d
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(d, "ms")), 64)
+		if err != nil {
+			return 0
+		}
+
+		ms = f
+	default:
+		return 0
+	}
+
+	return time.Duration(ms * float64(time.Millisecond))
+}
@@ -0,0 +1,124 @@
+package cherrypy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHighstate(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "local_highstate")
+
+	raw, err := c.RunCommand(context.Background(), Command{
+		Client:              LocalClient,
+		Target:              ExpressionTarget{Expression: "minion*", Type: Glob},
+		Function:            "state.apply",
+		PositionalArguments: []interface{}{"motd"},
+	})
+	assert.NoError(t, err)
+
+	res, err := ParseHighstate(raw)
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+
+	states := res["minion1"].States
+	assert.Len(t, states, 3)
+	assert.Equal(t, "figlet", states[0].ID)
+	assert.Equal(t, "motd", states[1].ID)
+	assert.Equal(t, "banner", states[2].ID)
+
+	motd := states[1]
+	assert.Equal(t, "file_|-motd_|-/etc/motd_|-managed", motd.Key)
+	assert.Equal(t, "/etc/motd", motd.Name)
+	assert.Equal(t, "file", motd.Module)
+	assert.Equal(t, "file.managed", motd.Function)
+	assert.True(t, *motd.Result)
+	assert.True(t, motd.Changed())
+	assert.Equal(t, "File /etc/motd updated", motd.Comment)
+	assert.Equal(t, "20:43:11.581218", motd.StartTime)
+	assert.Equal(t, 27611*time.Microsecond, motd.Duration)
+	assert.Equal(t, 1, motd.RunNum)
+	assert.Equal(t, "motd", motd.SLS)
+
+	assert.Equal(t, "figlet Welcome > /etc/issue", states[2].Name)
+	assert.False(t, states[2].Succeeded())
+
+	assert.Equal(t, StateSummary{
+		Succeeded: 2,
+		Failed:    1,
+		Changed:   1,
+		Total:     3,
+		Duration:  518438 * time.Microsecond,
+	}, res["minion1"].Summary())
+	assert.False(t, res["minion1"].Succeeded())
+
+	assert.Empty(t, res["minion2"].States)
+	assert.Equal(t, []string{"Rendering SLS 'base:motd' failed: Jinja variable 'dict object' has no attribute 'banner'"}, res["minion2"].Errors)
+	assert.False(t, res["minion2"].Succeeded())
+}
+
+func TestParseMinionStatesTestMode(t *testing.T) {
+	res, err := ParseMinionStates(map[string]interface{}{
+		"file_|-motd_|-/etc/motd_|-managed": map[string]interface{}{
+			"changes":     map[string]interface{}{"diff": "+Welcome"},
+			"comment":     []interface{}{"The file /etc/motd is set to be changed", "Note: No changes made, actual changes may be different due to other states."},
+			"result":      nil,
+			"__run_num__": 0,
+			"duration":    "3.2 ms",
+		},
+		"service_|-ntp_|-ntpd_|-running": map[string]interface{}{
+			"changes":     map[string]interface{}{},
+			"comment":     "The service ntpd is already running",
+			"result":      true,
+			"__run_num__": 1,
+			"duration":    1.8,
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Nil(t, res.States[0].Result)
+	assert.True(t, res.States[0].Succeeded())
+	assert.Equal(t, "motd", res.States[0].ID)
+	assert.Equal(t, "/etc/motd", res.States[0].Name)
+	assert.Contains(t, res.States[0].Comment, "\nNote: No changes made")
+	assert.Equal(t, 3200*time.Microsecond, res.States[0].Duration)
+	assert.Equal(t, "ntpd", res.States[1].Name)
+	assert.Equal(t, StateSummary{
+		Succeeded: 2,
+		Unchanged: 1,
+		Changed:   1,
+		Total:     2,
+		Duration:  5 * time.Millisecond,
+	}, res.Summary())
+	assert.True(t, res.Succeeded())
+}
+
+func TestParseMinionStatesInvalid(t *testing.T) {
+	_, err := ParseMinionStates(map[string]interface{}{"motd": map[string]interface{}{}})
+	assert.Error(t, err)
+
+	_, err = ParseMinionStates(true)
+	assert.Error(t, err)
+}
+
+func TestJobHighstate(t *testing.T) {
+	job := JobDetails{
+		Returns: map[string]interface{}{
+			"minion1": map[string]interface{}{
+				"pkg_|-figlet_|-figlet_|-installed": map[string]interface{}{
+					"result":  true,
+					"changes": map[string]interface{}{},
+				},
+			},
+		},
+	}
+
+	res, err := job.Highstate()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "pkg.installed", res["minion1"].States[0].Function)
+}
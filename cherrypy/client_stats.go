@@ -2,7 +2,6 @@ package cherrypy
 
 import (
 	"context"
-	"log"
 )
 
 /*
@@ -16,7 +15,7 @@ func (c *Client) Stats(ctx context.Context) (map[string]interface{}, error) {
 		return nil, err
 	}
 
-	log.Println("[DEBUG] Sending stats request")
+	c.debug("Sending stats request")
 	var resp map[string]interface{}
 	_, err = c.do(req, &resp)
 	if err != nil {
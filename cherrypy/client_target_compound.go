@@ -0,0 +1,512 @@
+package cherrypy
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"unicode"
+)
+
+// compoundEngines maps target types to the prefixes used in compound targets
+var compoundEngines = map[TargetType]string{
+	Glob:       "",
+	Grain:      "G",
+	GrainPCRE:  "P",
+	Pillar:     "I",
+	PillarPCRE: "J",
+	List:       "L",
+	IPCIDR:     "S",
+	PCRE:       "E",
+	NodeGroup:  "N",
+	Range:      "R",
+}
+
+/*
+CompoundExpression is a node of a compound target
+
+Expressions are built with And(), Or(), Not() and the Match functions (e.g.: MatchGrain())
+or parsed from a string with ParseCompoundTarget().
+
+https://docs.saltstack.com/en/latest/topics/targeting/compound.html
+*/
+type CompoundExpression interface {
+	// String renders the expression in the compound target syntax
+	String() string
+
+	// Validate reports whether the expression can be rendered into a valid compound target
+	Validate() error
+}
+
+// MatchExpression matches minions with a single target type
+type MatchExpression struct {
+	Type    TargetType
+	Pattern string
+
+	// Delimiter separates keys of grain and pillar targets; ":" is used if not set
+	Delimiter string
+}
+
+// AndExpression matches minions matched by all operands
+type AndExpression struct {
+	Operands []CompoundExpression
+}
+
+// OrExpression matches minions matched by any operand
+type OrExpression struct {
+	Operands []CompoundExpression
+}
+
+// NotExpression matches minions not matched by the operand
+type NotExpression struct {
+	Operand CompoundExpression
+}
+
+/*
+CompoundTarget is a validated compound target
+
+Example usage:
+	target, err := cherrypy.NewCompoundTarget(cherrypy.And(
+		cherrypy.MatchGrain("os:Ubuntu"),
+		cherrypy.Or(cherrypy.MatchGlob("web*"), cherrypy.MatchPCRE(`db\d+`)),
+		cherrypy.Not(cherrypy.MatchList("bad1", "bad2")),
+	))
+*/
+type CompoundTarget struct {
+	Expression CompoundExpression
+}
+
+// NewCompoundTarget validates the expression and returns a target using it
+func NewCompoundTarget(expr CompoundExpression) (*CompoundTarget, error) {
+	if compoundValue(expr) == nil {
+		return nil, fmt.Errorf("%w: empty expression", ErrorInvalidTarget)
+	}
+
+	if err := expr.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &CompoundTarget{Expression: expr}, nil
+}
+
+// GetTarget returns the rendered expression
+func (t CompoundTarget) GetTarget() interface{} {
+	return t.Expression.String()
+}
+
+// GetType returns target type
+func (t CompoundTarget) GetType() TargetType {
+	return Compound
+}
+
+// And returns an expression matching minions matched by all operands
+func And(operands ...CompoundExpression) AndExpression {
+	return AndExpression{Operands: operands}
+}
+
+// Or returns an expression matching minions matched by any operand
+func Or(operands ...CompoundExpression) OrExpression {
+	return OrExpression{Operands: operands}
+}
+
+// Not returns an expression matching minions not matched by the operand
+func Not(operand CompoundExpression) NotExpression {
+	return NotExpression{Operand: operand}
+}
+
+// MatchGlob matches minion IDs with a shell pattern (e.g.: web*)
+func MatchGlob(pattern string) MatchExpression {
+	return MatchExpression{Type: Glob, Pattern: pattern}
+}
+
+// MatchPCRE matches minion IDs with a regular expression (E@)
+func MatchPCRE(pattern string) MatchExpression {
+	return MatchExpression{Type: PCRE, Pattern: pattern}
+}
+
+// MatchList matches the minion IDs (L@)
+func MatchList(ids ...string) MatchExpression {
+	return MatchExpression{Type: List, Pattern: strings.Join(ids, ",")}
+}
+
+// MatchGrain matches grains with a shell pattern (G@), e.g.: os:Ubuntu
+func MatchGrain(expr string) MatchExpression {
+	return MatchExpression{Type: Grain, Pattern: expr}
+}
+
+// MatchGrainPCRE matches grains with a regular expression (P@), e.g.: os:(Ubuntu|CentOS)
+func MatchGrainPCRE(expr string) MatchExpression {
+	return MatchExpression{Type: GrainPCRE, Pattern: expr}
+}
+
+// MatchPillar matches pillar data with a shell pattern (I@), e.g.: app:env:prod
+func MatchPillar(expr string) MatchExpression {
+	return MatchExpression{Type: Pillar, Pattern: expr}
+}
+
+// MatchPillarPCRE matches pillar data with a regular expression (J@)
+func MatchPillarPCRE(expr string) MatchExpression {
+	return MatchExpression{Type: PillarPCRE, Pattern: expr}
+}
+
+// MatchIPCIDR matches minions with an IP address or in a subnet (S@), e.g.: 10.0.0.0/8
+func MatchIPCIDR(expr string) MatchExpression {
+	return MatchExpression{Type: IPCIDR, Pattern: expr}
+}
+
+// MatchNodeGroup matches minions in a nodegroup defined on the master (N@)
+func MatchNodeGroup(name string) MatchExpression {
+	return MatchExpression{Type: NodeGroup, Pattern: name}
+}
+
+// MatchRange matches minions with a range expression (R@)
+func MatchRange(expr string) MatchExpression {
+	return MatchExpression{Type: Range, Pattern: expr}
+}
+
+func (e MatchExpression) String() string {
+	engine := compoundEngines[e.Type]
+	if engine == "" {
+		return e.Pattern
+	}
+
+	delimiter := ""
+	if e.Delimiter != "" && e.Delimiter != defaultTargetDelimiter {
+		delimiter = e.Delimiter
+	}
+
+	return engine + delimiter + "@" + e.Pattern
+}
+
+// Validate reports whether the expression can be rendered into a valid compound target
+func (e MatchExpression) Validate() error {
+	engine, ok := compoundEngines[e.Type]
+	if !ok {
+		return fmt.Errorf("%w: %q cannot be used in compound targets", ErrorInvalidTarget, e.Type)
+	}
+
+	if e.Pattern == "" {
+		return fmt.Errorf("%w: empty %s pattern", ErrorInvalidTarget, e.Type)
+	}
+
+	if strings.IndexFunc(e.Pattern, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("%w: %q contains whitespace", ErrorInvalidTarget, e.Pattern)
+	}
+
+	delimiter := defaultTargetDelimiter
+	if e.Delimiter != "" {
+		switch {
+		case engine != "G" && engine != "P" && engine != "I" && engine != "J":
+			return fmt.Errorf("%w: delimiter cannot be used with %s", ErrorInvalidTarget, e.Type)
+		case len([]rune(e.Delimiter)) != 1 || e.Delimiter == "@" || strings.IndexFunc(e.Delimiter, unicode.IsSpace) >= 0:
+			return fmt.Errorf("%w: invalid delimiter %q", ErrorInvalidTarget, e.Delimiter)
+		}
+
+		delimiter = e.Delimiter
+	}
+
+	switch e.Type {
+	case Glob:
+		// Patterns looking like operators or other matchers would be read differently by the master
+		if isCompoundOperator(e.Pattern) || compoundTargetRegexp.FindStringSubmatch(e.Pattern)[1] != "" {
+			return fmt.Errorf("%w: glob %q is ambiguous", ErrorInvalidTarget, e.Pattern)
+		}
+	case Grain, GrainPCRE, Pillar, PillarPCRE:
+		if !strings.Contains(e.Pattern, delimiter) {
+			return fmt.Errorf("%w: %q does not contain delimiter %q", ErrorInvalidTarget, e.Pattern, delimiter)
+		}
+	case List:
+		for _, id := range strings.Split(e.Pattern, ",") {
+			if id == "" {
+				return fmt.Errorf("%w: empty minion ID in list %q", ErrorInvalidTarget, e.Pattern)
+			}
+		}
+	case IPCIDR:
+		if _, err := parseIPCIDR(e.Pattern); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e AndExpression) String() string {
+	return joinOperands(e.Operands, "and", func(op CompoundExpression) bool {
+		_, ok := compoundValue(op).(OrExpression)
+		return ok
+	})
+}
+
+// Validate reports whether the expression can be rendered into a valid compound target
+func (e AndExpression) Validate() error {
+	return validateOperands(e.Operands, "and")
+}
+
+func (e OrExpression) String() string {
+	return joinOperands(e.Operands, "or", func(op CompoundExpression) bool {
+		return false
+	})
+}
+
+// Validate reports whether the expression can be rendered into a valid compound target
+func (e OrExpression) Validate() error {
+	return validateOperands(e.Operands, "or")
+}
+
+func (e NotExpression) String() string {
+	if _, ok := compoundValue(e.Operand).(MatchExpression); ok {
+		return "not " + e.Operand.String()
+	}
+
+	return "not ( " + e.Operand.String() + " )"
+}
+
+// Validate reports whether the expression can be rendered into a valid compound target
+func (e NotExpression) Validate() error {
+	if compoundValue(e.Operand) == nil {
+		return fmt.Errorf("%w: not without operand", ErrorInvalidTarget)
+	}
+
+	return e.Operand.Validate()
+}
+
+// joinOperands renders operands with the operator; operands with lower precedence are wrapped in parentheses
+func joinOperands(operands []CompoundExpression, operator string, group func(CompoundExpression) bool) string {
+	words := make([]string, len(operands))
+	for i, op := range operands {
+		words[i] = op.String()
+		if group(op) {
+			words[i] = "( " + words[i] + " )"
+		}
+	}
+
+	return strings.Join(words, " "+operator+" ")
+}
+
+func validateOperands(operands []CompoundExpression, operator string) error {
+	if len(operands) == 0 {
+		return fmt.Errorf("%w: %s without operands", ErrorInvalidTarget, operator)
+	}
+
+	for _, op := range operands {
+		if compoundValue(op) == nil {
+			return fmt.Errorf("%w: %s with empty operand", ErrorInvalidTarget, operator)
+		}
+
+		if err := op.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compoundValue dereferences pointers to expressions so both forms are handled alike
+func compoundValue(expr CompoundExpression) CompoundExpression {
+	switch e := expr.(type) {
+	case *MatchExpression:
+		if e != nil {
+			return *e
+		}
+	case *AndExpression:
+		if e != nil {
+			return *e
+		}
+	case *OrExpression:
+		if e != nil {
+			return *e
+		}
+	case *NotExpression:
+		if e != nil {
+			return *e
+		}
+	default:
+		return expr
+	}
+
+	return nil
+}
+
+func isCompoundOperator(word string) bool {
+	switch word {
+	case "and", "or", "not", "(", ")":
+		return true
+	}
+
+	return false
+}
+
+/*
+ParseCompoundTarget parses a compound target into an expression tree and validates it
+
+Like Salt, "not" following an operand is read as "and not" (e.g.: web* not web1).
+*/
+func ParseCompoundTarget(expr string) (*CompoundTarget, error) {
+	root, err := parseCompoundExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCompoundTarget(root)
+}
+
+func parseCompoundExpression(expr string) (CompoundExpression, error) {
+	p := compoundParser{tokens: strings.Fields(expr)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("%w: empty expression", ErrorInvalidTarget)
+	}
+
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrorInvalidTarget, p.tokens[p.pos])
+	}
+
+	return root, nil
+}
+
+type compoundParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *compoundParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+
+	return ""
+}
+
+func (p *compoundParser) or() (CompoundExpression, error) {
+	first, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	operands := []CompoundExpression{first}
+	for p.peek() == "or" {
+		p.pos++
+		op, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+
+		operands = append(operands, op)
+	}
+
+	if len(operands) == 1 {
+		return first, nil
+	}
+
+	return OrExpression{Operands: operands}, nil
+}
+
+func (p *compoundParser) and() (CompoundExpression, error) {
+	first, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	operands := []CompoundExpression{first}
+	for {
+		switch p.peek() {
+		case "and":
+			p.pos++
+		case "not":
+			// Salt adds "and" before "not" following an operand
+		default:
+			if len(operands) == 1 {
+				return first, nil
+			}
+
+			return AndExpression{Operands: operands}, nil
+		}
+
+		op, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+
+		operands = append(operands, op)
+	}
+}
+
+func (p *compoundParser) not() (CompoundExpression, error) {
+	if p.peek() != "not" {
+		return p.primary()
+	}
+
+	p.pos++
+	if p.peek() == "not" {
+		// Salt reads "not not" as "not and not"
+		return nil, fmt.Errorf("%w: unexpected \"not\" after \"not\"", ErrorInvalidTarget)
+	}
+
+	op, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	return NotExpression{Operand: op}, nil
+}
+
+func (p *compoundParser) primary() (CompoundExpression, error) {
+	token := p.peek()
+	p.pos++
+
+	switch token {
+	case "":
+		return nil, fmt.Errorf("%w: unexpected end of expression", ErrorInvalidTarget)
+	case "(":
+		if next := p.peek(); next == "and" || next == "or" {
+			return nil, fmt.Errorf("%w: invalid operator %q after \"(\"", ErrorInvalidTarget, next)
+		}
+
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("%w: missing \")\"", ErrorInvalidTarget)
+		}
+
+		p.pos++
+		return expr, nil
+	case "and", "or", ")":
+		return nil, fmt.Errorf("%w: unexpected %q", ErrorInvalidTarget, token)
+	}
+
+	parts := compoundTargetRegexp.FindStringSubmatch(token)
+	engine, delimiter, pattern := parts[1], parts[2], parts[3]
+	for t, e := range compoundEngines {
+		if e == engine {
+			return MatchExpression{Type: t, Pattern: pattern, Delimiter: delimiter}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: unknown matcher %q", ErrorInvalidTarget, token)
+}
+
+// parseIPCIDR parses an IP address or a subnet of an ipcidr target
+func parseIPCIDR(expr string) (*net.IPNet, error) {
+	if ip := net.ParseIP(expr); ip != nil {
+		bits := 128
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 32
+		}
+
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	ip, subnet, err := net.ParseCIDR(expr)
+	if err != nil || !ip.Equal(subnet.IP) {
+		return nil, fmt.Errorf("%w: invalid IP/CIDR target %q", ErrorInvalidTarget, expr)
+	}
+
+	return subnet, nil
+}
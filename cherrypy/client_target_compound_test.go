@@ -0,0 +1,168 @@
+package cherrypy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompoundTargetString(t *testing.T) {
+	cases := []struct {
+		expr     CompoundExpression
+		expected string
+	}{
+		{MatchGlob("web*"), "web*"},
+		{MatchGrain("os:Ubuntu"), "G@os:Ubuntu"},
+		{MatchExpression{Type: Grain, Pattern: "roles%database", Delimiter: "%"}, "G%@roles%database"},
+		{MatchExpression{Type: Pillar, Pattern: "app:env:prod", Delimiter: ":"}, "I@app:env:prod"},
+		{MatchGrainPCRE("os:(Ubuntu|CentOS)"), "P@os:(Ubuntu|CentOS)"},
+		{MatchPillarPCRE("app:env:st.*"), "J@app:env:st.*"},
+		{MatchPCRE(`db\d+`), `E@db\d+`},
+		{MatchList("web1", "web2"), "L@web1,web2"},
+		{MatchIPCIDR("10.0.0.0/8"), "S@10.0.0.0/8"},
+		{MatchNodeGroup("webservers"), "N@webservers"},
+		{MatchRange("%cluster"), "R@%cluster"},
+		{And(MatchGlob("web*"), MatchGrain("os:Ubuntu")), "web* and G@os:Ubuntu"},
+		{Or(MatchGlob("web*"), And(MatchGlob("db*"), MatchGrain("os:CentOS"))), "web* or db* and G@os:CentOS"},
+		{And(Or(MatchGlob("web*"), MatchGlob("db*")), MatchGrain("os:Ubuntu")), "( web* or db* ) and G@os:Ubuntu"},
+		{Not(MatchGlob("web1")), "not web1"},
+		{Not(Or(MatchGlob("web1"), MatchGlob("web2"))), "not ( web1 or web2 )"},
+		{Not(Not(MatchGlob("web1"))), "not ( not web1 )"},
+		{&AndExpression{Operands: []CompoundExpression{&OrExpression{Operands: []CompoundExpression{MatchGlob("a"), MatchGlob("b")}}, MatchGlob("c")}}, "( a or b ) and c"},
+	}
+
+	for _, tc := range cases {
+		target, err := NewCompoundTarget(tc.expr)
+
+		assert.NoError(t, err, tc.expected)
+		if assert.NotNil(t, target, tc.expected) {
+			assert.Equal(t, tc.expected, target.GetTarget())
+			assert.Equal(t, TargetType(Compound), target.GetType())
+		}
+	}
+}
+
+func TestParseCompoundTarget(t *testing.T) {
+	cases := []struct {
+		expr     string
+		expected CompoundExpression
+	}{
+		{"web*", MatchGlob("web*")},
+		{"G%@roles%database", MatchExpression{Type: Grain, Pattern: "roles%database", Delimiter: "%"}},
+		{
+			"G@os:Ubuntu and ( web* or E@db\\d+ ) not L@web2,bad1",
+			And(MatchGrain("os:Ubuntu"), Or(MatchGlob("web*"), MatchPCRE(`db\d+`)), Not(MatchList("web2", "bad1"))),
+		},
+		{"web1 or db10 and G@os:ubuntu", Or(MatchGlob("web1"), And(MatchGlob("db10"), MatchGrain("os:ubuntu")))},
+		{"a or b or c", Or(MatchGlob("a"), MatchGlob("b"), MatchGlob("c"))},
+		{"( ( a ) )", MatchGlob("a")},
+		{"not ( a and b ) or not N@group", Or(Not(And(MatchGlob("a"), MatchGlob("b"))), Not(MatchNodeGroup("group")))},
+		{"S@10.0.0.0/16   and\tR@%cluster", And(MatchIPCIDR("10.0.0.0/16"), MatchRange("%cluster"))},
+	}
+
+	for _, tc := range cases {
+		target, err := ParseCompoundTarget(tc.expr)
+
+		assert.NoError(t, err, tc.expr)
+		if assert.NotNil(t, target, tc.expr) {
+			assert.Equal(t, tc.expected, target.Expression, tc.expr)
+
+			// Rendered expressions are parsed into the same tree
+			again, err := ParseCompoundTarget(target.GetTarget().(string))
+			assert.NoError(t, err, tc.expr)
+			assert.Equal(t, target, again, tc.expr)
+		}
+	}
+}
+
+func TestCompoundTargetErrors(t *testing.T) {
+	parsed := []string{
+		"",
+		"and web*",
+		"or web*",
+		"( or web* )",
+		"( web*",
+		"web* )",
+		"web* and",
+		"web* web2",
+		"not not web*",
+		"( )",
+		"web* and not",
+		"G@os",
+		"S@10.0.1.1/24",
+		"L@web1,,web2",
+	}
+
+	for _, expr := range parsed {
+		_, err := ParseCompoundTarget(expr)
+
+		assert.True(t, errors.Is(err, ErrorInvalidTarget), "%q: %v", expr, err)
+	}
+
+	built := []CompoundExpression{
+		nil,
+		(*AndExpression)(nil),
+		And(),
+		Or(MatchGlob("web*"), nil),
+		Not(nil),
+		MatchGlob(""),
+		MatchGlob("web* or db*"),
+		MatchGlob("and"),
+		MatchGlob("G@os:Ubuntu"),
+		MatchGrain("os"),
+		MatchExpression{Type: Grain, Pattern: "os%Ubuntu", Delimiter: "%%"},
+		MatchExpression{Type: Grain, Pattern: "os@Ubuntu", Delimiter: "@"},
+		MatchExpression{Type: PCRE, Pattern: "web.*", Delimiter: "%"},
+		MatchExpression{Type: Compound, Pattern: "web*"},
+		MatchList(),
+		MatchList("web1", ""),
+		MatchIPCIDR("10.0.0.256"),
+		And(MatchGlob("web*"), Not(MatchIPCIDR("10.0.1.1/24"))),
+	}
+
+	for _, expr := range built {
+		_, err := NewCompoundTarget(expr)
+
+		assert.True(t, errors.Is(err, ErrorInvalidTarget), "%#v: %v", expr, err)
+	}
+}
+
+func TestCompoundTargetMatcher(t *testing.T) {
+	m := testMatcher()
+
+	target, err := NewCompoundTarget(And(
+		MatchNodeGroup("webservers"),
+		Not(MatchExpression{Type: Grain, Pattern: "ipv4%10.0.1.12", Delimiter: "%"}),
+	))
+	assert.NoError(t, err)
+
+	res, err := m.Match(target)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web1"}, res)
+}
+
+func TestRunCompoundTarget(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "run", "local_compound")
+
+	target, err := NewCompoundTarget(And(
+		MatchGrain("os:Ubuntu"),
+		Or(MatchGlob("web*"), MatchPCRE(`db\d+`)),
+		Not(MatchList("web2", "bad1")),
+	))
+	assert.NoError(t, err)
+
+	res, err := c.RunLocalCommand(context.Background(), Command{
+		Target:   target,
+		Function: "test.ping",
+	})
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, res) {
+		assert.Len(t, res, 1)
+	}
+}
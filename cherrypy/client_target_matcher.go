@@ -0,0 +1,515 @@
+package cherrypy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultTargetDelimiter separates keys of grain and pillar targets
+const defaultTargetDelimiter = ":"
+
+var (
+	// ErrorUnsupportedTarget indicates the target type cannot be evaluated without the master (e.g.: range)
+	ErrorUnsupportedTarget = errors.New("target type cannot be evaluated locally")
+
+	// ErrorInvalidTarget indicates the target expression is malformed
+	ErrorInvalidTarget = errors.New("invalid target")
+
+	// ErrorUnknownNodeGroup indicates the nodegroup is not defined in the matcher
+	ErrorUnknownNodeGroup = errors.New("unknown nodegroup")
+)
+
+// compoundTargetRegexp parses a word of a compound target (e.g.: G@os:Ubuntu or G%@os%Ubuntu)
+var compoundTargetRegexp = regexp.MustCompile(`^(?:([GPIJLNSER])([^@])?@)?(.+)$`)
+
+/*
+Matcher evaluates targets locally following the matching rules of Salt minions
+
+Grain targets are evaluated with the grains of minions; pillar targets with Pillars.
+Range targets are not supported.
+
+https://docs.saltstack.com/en/latest/topics/targeting/index.html
+*/
+type Matcher struct {
+	// Minions to match (e.g.: returned by Client.Minions())
+	Minions []Minion
+
+	// Pillars contain pillar data per minion ID used for pillar targets
+	Pillars map[string]map[string]interface{}
+
+	// NodeGroups contain compound expressions per nodegroup name like the nodegroups option of the master
+	NodeGroups map[string]string
+}
+
+/*
+PreviewTarget returns IDs of minions the target would match
+
+Minions and their grains are retrieved with Minions(); pillar targets cannot be evaluated
+and nodegroups are unknown. Use Matcher directly to provide them.
+*/
+func (c *Client) PreviewTarget(ctx context.Context, t Target) ([]string, error) {
+	minions, err := c.Minions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m := Matcher{Minions: minions}
+	return m.Match(t)
+}
+
+// Match returns sorted IDs of minions matching the target
+func (m *Matcher) Match(t Target) ([]string, error) {
+	ids := []string{}
+	for _, minion := range m.Minions {
+		ok, err := m.Matches(t, minion)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			ids = append(ids, minion.ID)
+		}
+	}
+
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Matches reports whether the target matches the minion
+func (m *Matcher) Matches(t Target, minion Minion) (bool, error) {
+	if t == nil {
+		return false, fmt.Errorf("%w: target is not set", ErrorInvalidTarget)
+	}
+
+	if l, ok := t.(ListTarget); ok {
+		return matchList(l.Targets, minion.ID), nil
+	}
+
+	if l, ok := t.(*ListTarget); ok {
+		return matchList(l.Targets, minion.ID), nil
+	}
+
+	expr, ok := t.GetTarget().(string)
+	if !ok {
+		return false, fmt.Errorf("%w: unexpected expression %T", ErrorInvalidTarget, t.GetTarget())
+	}
+
+	return m.match(t.GetType(), expr, defaultTargetDelimiter, minion)
+}
+
+func (m *Matcher) match(targetType TargetType, expr string, delimiter string, minion Minion) (bool, error) {
+	switch targetType {
+	case Glob, "":
+		return fnmatch(minion.ID, expr), nil
+	case PCRE:
+		re, err := regexp.Compile("^(?:" + expr + ")")
+		if err != nil {
+			return false, fmt.Errorf("%w: %s", ErrorInvalidTarget, err)
+		}
+
+		return re.MatchString(minion.ID), nil
+	case List:
+		return matchList(strings.Split(expr, ","), minion.ID), nil
+	case Grain:
+		return subdictMatch(minion.Grains, expr, delimiter, false), nil
+	case GrainPCRE:
+		return subdictMatch(minion.Grains, expr, delimiter, true), nil
+	case Pillar:
+		return subdictMatch(m.Pillars[minion.ID], expr, delimiter, false), nil
+	case PillarPCRE:
+		return subdictMatch(m.Pillars[minion.ID], expr, delimiter, true), nil
+	case IPCIDR:
+		return matchIPCIDR(minion.Grains, expr)
+	case NodeGroup:
+		return m.matchNodeGroup(expr, nil, minion)
+	case Compound:
+		root, err := parseCompoundExpression(expr)
+		if err != nil {
+			return false, err
+		}
+
+		return m.evalCompound(root, nil, minion)
+	default:
+		return false, fmt.Errorf("%w: %s", ErrorUnsupportedTarget, targetType)
+	}
+}
+
+/*
+evalCompound evaluates the expression tree like Salt's compound matcher
+
+All matchers are evaluated before combining results so invalid ones are reported
+regardless of the operators; seen contains nodegroups being expanded.
+*/
+func (m *Matcher) evalCompound(expr CompoundExpression, seen map[string]bool, minion Minion) (bool, error) {
+	switch e := compoundValue(expr).(type) {
+	case MatchExpression:
+		if e.Type == NodeGroup {
+			return m.matchNodeGroup(e.Pattern, seen, minion)
+		}
+
+		delimiter := e.Delimiter
+		if delimiter == "" {
+			delimiter = defaultTargetDelimiter
+		}
+
+		return m.match(e.Type, e.Pattern, delimiter, minion)
+	case AndExpression:
+		results, err := m.evalOperands(e.Operands, seen, minion)
+		if err != nil {
+			return false, err
+		}
+
+		for _, ok := range results {
+			if !ok {
+				return false, nil
+			}
+		}
+
+		return true, nil
+	case OrExpression:
+		results, err := m.evalOperands(e.Operands, seen, minion)
+		if err != nil {
+			return false, err
+		}
+
+		for _, ok := range results {
+			if ok {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	case NotExpression:
+		ok, err := m.evalCompound(e.Operand, seen, minion)
+		return !ok, err
+	case nil:
+		return false, fmt.Errorf("%w: empty expression", ErrorInvalidTarget)
+	default:
+		return false, fmt.Errorf("%w: unexpected expression %T", ErrorInvalidTarget, expr)
+	}
+}
+
+func (m *Matcher) evalOperands(operands []CompoundExpression, seen map[string]bool, minion Minion) ([]bool, error) {
+	if len(operands) == 0 {
+		return nil, fmt.Errorf("%w: operator without operands", ErrorInvalidTarget)
+	}
+
+	results := make([]bool, len(operands))
+	for i, op := range operands {
+		ok, err := m.evalCompound(op, seen, minion)
+		if err != nil {
+			return nil, err
+		}
+
+		results[i] = ok
+	}
+
+	return results, nil
+}
+
+// matchNodeGroup evaluates the compound expression of the nodegroup; nested nodegroups are expanded in place
+func (m *Matcher) matchNodeGroup(name string, seen map[string]bool, minion Minion) (bool, error) {
+	expr, ok := m.NodeGroups[name]
+	if !ok {
+		return false, fmt.Errorf("%w: %s", ErrorUnknownNodeGroup, name)
+	}
+
+	if seen[name] {
+		return false, fmt.Errorf("%w: nodegroup %s includes itself", ErrorInvalidTarget, name)
+	}
+
+	root, err := parseCompoundExpression(expr)
+	if err != nil {
+		return false, fmt.Errorf("nodegroup %s: %w", name, err)
+	}
+
+	nested := map[string]bool{name: true}
+	for k := range seen {
+		nested[k] = true
+	}
+
+	return m.evalCompound(root, nested, minion)
+}
+
+func matchList(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchIPCIDR matches an IP address or a subnet with ipv4 and ipv6 grains
+func matchIPCIDR(grains map[string]interface{}, expr string) (bool, error) {
+	subnet, err := parseIPCIDR(expr)
+	if err != nil {
+		return false, err
+	}
+
+	proto := "ipv6"
+	if subnet.IP.To4() != nil {
+		proto = "ipv4"
+	}
+
+	addrs, _ := grains[proto].([]interface{})
+	for _, a := range addrs {
+		s, _ := a.(string)
+		addr := net.ParseIP(s)
+		if addr != nil && subnet.Contains(addr) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+/*
+subdictMatch matches a delimited expression (e.g.: os:Ubuntu) with nested data like salt.utils.data.subdict_match
+
+Values are compared case-insensitively with fnmatch or with a regular expression anchored at the start.
+*/
+func subdictMatch(data map[string]interface{}, expr string, delimiter string, regex bool) bool {
+	splits := strings.Split(expr, delimiter)
+	if len(splits) == 1 || data == nil {
+		return false
+	}
+
+	for i := 1; i < len(splits); i++ {
+		key := strings.Join(splits[:i], delimiter)
+		pattern := strings.Join(splits[i:], delimiter)
+
+		var value interface{}
+		if key == "*" {
+			// Matching everything under the top level
+			pattern = expr
+			value = data
+		} else {
+			var ok bool
+			value, ok = traverseData(data, key, delimiter)
+			if !ok {
+				continue
+			}
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if len(v) > 0 && matchDict(v, pattern, delimiter, regex) {
+				return true
+			}
+		case []interface{}:
+			for _, member := range v {
+				if d, ok := member.(map[string]interface{}); ok && matchDict(d, pattern, delimiter, regex) {
+					return true
+				}
+
+				if matchValue(member, pattern, regex) {
+					return true
+				}
+			}
+		default:
+			if matchValue(v, pattern, regex) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func matchDict(data map[string]interface{}, pattern string, delimiter string, regex bool) bool {
+	wildcard := strings.HasPrefix(pattern, "*"+delimiter)
+	if wildcard {
+		pattern = pattern[1+len(delimiter):]
+	}
+
+	// Checking if the key exists
+	if pattern == "*" {
+		return true
+	}
+
+	if _, ok := data[pattern]; ok {
+		return true
+	}
+
+	if subdictMatch(data, pattern, delimiter, regex) {
+		return true
+	}
+
+	if !wildcard {
+		return false
+	}
+
+	for _, v := range data {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			if matchDict(val, pattern, delimiter, regex) {
+				return true
+			}
+		case []interface{}:
+			for _, item := range val {
+				if matchValue(item, pattern, regex) {
+					return true
+				}
+			}
+		default:
+			if matchValue(val, pattern, regex) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func matchValue(v interface{}, pattern string, regex bool) bool {
+	target := strings.ToLower(pythonString(v))
+	pattern = strings.ToLower(pattern)
+
+	if regex {
+		re, err := regexp.Compile("^(?:" + pattern + ")")
+		if err != nil {
+			return false
+		}
+
+		return re.MatchString(target)
+	}
+
+	return fnmatch(target, pattern)
+}
+
+/*
+traverseData returns the value at the delimited path (e.g.: ip_interfaces:eth0:0)
+like salt.utils.data.traverse_dict_and_list; numeric keys are used as list indexes
+*/
+func traverseData(data map[string]interface{}, path string, delimiter string) (interface{}, bool) {
+	var ptr interface{} = data
+	for _, key := range strings.Split(path, delimiter) {
+		switch v := ptr.(type) {
+		case map[string]interface{}:
+			next, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+
+			ptr = next
+		case []interface{}:
+			// Dictionaries embedded in the list are searched first
+			found := false
+			for _, item := range v {
+				if d, ok := item.(map[string]interface{}); ok {
+					if next, ok := d[key]; ok {
+						ptr = next
+						found = true
+						break
+					}
+				}
+			}
+
+			if found {
+				continue
+			}
+
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx >= len(v) || idx < -len(v) {
+				return nil, false
+			}
+
+			if idx < 0 {
+				idx += len(v)
+			}
+
+			ptr = v[idx]
+		default:
+			return nil, false
+		}
+	}
+
+	return ptr, true
+}
+
+// pythonString formats the value like str() of Python so grain values are compared as Salt does
+func pythonString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "None"
+	case string:
+		return val
+	case bool:
+		return formatBool(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// fnmatch matches the name with a shell pattern like fnmatch.fnmatch of Python on POSIX systems
+func fnmatch(name string, pattern string) bool {
+	re, err := regexp.Compile(fnmatchRegexp(pattern))
+	if err != nil {
+		return false
+	}
+
+	return re.MatchString(name)
+}
+
+// fnmatchRegexp translates a shell pattern into a regular expression like fnmatch.translate of Python
+func fnmatchRegexp(pattern string) string {
+	p := []rune(pattern)
+
+	var b strings.Builder
+	b.WriteString("^(?s:")
+	for i := 0; i < len(p); {
+		c := p[i]
+		i++
+
+		switch c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			j := i
+			if j < len(p) && p[j] == '!' {
+				j++
+			}
+
+			if j < len(p) && p[j] == ']' {
+				j++
+			}
+
+			for j < len(p) && p[j] != ']' {
+				j++
+			}
+
+			if j >= len(p) {
+				b.WriteString(`\[`)
+				continue
+			}
+
+			class := strings.Replace(string(p[i:j]), `\`, `\\`, -1)
+			i = j + 1
+
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			} else if strings.HasPrefix(class, "^") {
+				class = `\` + class
+			}
+
+			b.WriteString("[" + class + "]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	b.WriteString(")$")
+	return b.String()
+}
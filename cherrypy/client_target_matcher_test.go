@@ -0,0 +1,159 @@
+package cherrypy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testMatcher() *Matcher {
+	return &Matcher{
+		Minions: []Minion{
+			{
+				ID: "web1",
+				Grains: map[string]interface{}{
+					"os":        "Ubuntu",
+					"osrelease": "18.04",
+					"num_cpus":  4.0,
+					"ipv4":      []interface{}{"127.0.0.1", "10.0.1.11"},
+					"ipv6":      []interface{}{"::1", "fe80::a00:27ff:fe93:ab66"},
+					"roles":     []interface{}{"web", "frontend"},
+					"ip_interfaces": map[string]interface{}{
+						"eth0": []interface{}{"10.0.1.11"},
+					},
+					"virtual": false,
+				},
+			},
+			{
+				ID: "web2",
+				Grains: map[string]interface{}{
+					"os":       "ubuntu",
+					"num_cpus": 2.0,
+					"ipv4":     []interface{}{"127.0.0.1", "10.0.1.12"},
+					"roles":    []interface{}{"web"},
+				},
+			},
+			{
+				ID: "db10",
+				Grains: map[string]interface{}{
+					"os":       "CentOS",
+					"num_cpus": 8.0,
+					"ipv4":     []interface{}{"127.0.0.1", "10.0.2.10"},
+					"roles":    []interface{}{map[string]interface{}{"database": "primary"}},
+				},
+			},
+			{
+				ID: "bad1",
+			},
+		},
+		Pillars: map[string]map[string]interface{}{
+			"web1": {"app": map[string]interface{}{"env": "prod"}},
+			"db10": {"app": map[string]interface{}{"env": "staging"}},
+		},
+		NodeGroups: map[string]string{
+			"webservers": "web*",
+			"prod":       "N@webservers and I@app:env:prod",
+			"loop":       "N@loop",
+		},
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	m := testMatcher()
+
+	cases := []struct {
+		target   Target
+		expected []string
+	}{
+		{ExpressionTarget{Expression: "web*", Type: Glob}, []string{"web1", "web2"}},
+		{ExpressionTarget{Expression: "web[!1]", Type: Glob}, []string{"web2"}},
+		{ExpressionTarget{Expression: "?b1?", Type: Glob}, []string{"db10"}},
+		{ExpressionTarget{Expression: "WEB*", Type: Glob}, []string{}},
+		{ExpressionTarget{Expression: `db\d+`, Type: PCRE}, []string{"db10"}},
+		{ExpressionTarget{Expression: `b`, Type: PCRE}, []string{"bad1"}},
+		{ExpressionTarget{Expression: "web1,bad1", Type: List}, []string{"bad1", "web1"}},
+		{ListTarget{Targets: []string{"web2", "unknown"}}, []string{"web2"}},
+		{&ListTarget{Targets: []string{"db10"}}, []string{"db10"}},
+		{ExpressionTarget{Expression: "os:ubuntu", Type: Grain}, []string{"web1", "web2"}},
+		{ExpressionTarget{Expression: "os:Cent*", Type: Grain}, []string{"db10"}},
+		{ExpressionTarget{Expression: "num_cpus:4", Type: Grain}, []string{"web1"}},
+		{ExpressionTarget{Expression: "virtual:False", Type: Grain}, []string{"web1"}},
+		{ExpressionTarget{Expression: "roles:web", Type: Grain}, []string{"web1", "web2"}},
+		{ExpressionTarget{Expression: "roles:database:primary", Type: Grain}, []string{"db10"}},
+		{ExpressionTarget{Expression: "ip_interfaces:eth0:10.0.1.*", Type: Grain}, []string{"web1"}},
+		{ExpressionTarget{Expression: "ip_interfaces:eth0", Type: Grain}, []string{"web1"}},
+		{ExpressionTarget{Expression: "os", Type: Grain}, []string{}},
+		{ExpressionTarget{Expression: `os:(ubuntu|centos)`, Type: GrainPCRE}, []string{"db10", "web1", "web2"}},
+		{ExpressionTarget{Expression: "app:env:prod", Type: Pillar}, []string{"web1"}},
+		{ExpressionTarget{Expression: "app:env:st.*", Type: PillarPCRE}, []string{"db10"}},
+		{ExpressionTarget{Expression: "10.0.1.0/24", Type: IPCIDR}, []string{"web1", "web2"}},
+		{ExpressionTarget{Expression: "10.0.2.10", Type: IPCIDR}, []string{"db10"}},
+		{ExpressionTarget{Expression: "fe80::/64", Type: IPCIDR}, []string{"web1"}},
+		{ExpressionTarget{Expression: "webservers", Type: NodeGroup}, []string{"web1", "web2"}},
+		{ExpressionTarget{Expression: "prod", Type: NodeGroup}, []string{"web1"}},
+		{ExpressionTarget{Expression: "G@os:Ubuntu and ( web* or E@db\\d+ ) not L@web2,bad1", Type: Compound}, []string{"web1"}},
+		{ExpressionTarget{Expression: "not G@os:Ubuntu", Type: Compound}, []string{"bad1", "db10"}},
+		{ExpressionTarget{Expression: "web1 or db10 and G@os:ubuntu", Type: Compound}, []string{"web1"}},
+		{ExpressionTarget{Expression: "S@10.0.0.0/16 and not N@webservers", Type: Compound}, []string{"db10"}},
+		{ExpressionTarget{Expression: "G%@roles%database%primary", Type: Compound}, []string{"db10"}},
+	}
+
+	for _, tc := range cases {
+		res, err := m.Match(tc.target)
+
+		assert.NoError(t, err, "%v", tc.target)
+		assert.Equal(t, tc.expected, res, "%v", tc.target)
+	}
+}
+
+func TestMatcherErrors(t *testing.T) {
+	m := testMatcher()
+
+	cases := []struct {
+		target Target
+		err    error
+	}{
+		{ExpressionTarget{Expression: "and web*", Type: Compound}, ErrorInvalidTarget},
+		{ExpressionTarget{Expression: "( or web* )", Type: Compound}, ErrorInvalidTarget},
+		{ExpressionTarget{Expression: "( web*", Type: Compound}, ErrorInvalidTarget},
+		{ExpressionTarget{Expression: "web* )", Type: Compound}, ErrorInvalidTarget},
+		{ExpressionTarget{Expression: "web* and", Type: Compound}, ErrorInvalidTarget},
+		{ExpressionTarget{Expression: "10.0.1.1/24", Type: IPCIDR}, ErrorInvalidTarget},
+		{ExpressionTarget{Expression: "(", Type: PCRE}, ErrorInvalidTarget},
+		{ExpressionTarget{Expression: "missing", Type: NodeGroup}, ErrorUnknownNodeGroup},
+		{ExpressionTarget{Expression: "loop", Type: NodeGroup}, ErrorInvalidTarget},
+		{ExpressionTarget{Expression: "R@%cluster", Type: Compound}, ErrorUnsupportedTarget},
+		{ExpressionTarget{Expression: "%cluster", Type: Range}, ErrorUnsupportedTarget},
+		{nil, ErrorInvalidTarget},
+	}
+
+	for _, tc := range cases {
+		_, err := m.Match(tc.target)
+
+		assert.True(t, errors.Is(err, tc.err), "%v: %v", tc.target, err)
+	}
+}
+
+func TestFnmatchRegexp(t *testing.T) {
+	assert.Equal(t, `^(?s:web.*)$`, fnmatchRegexp("web*"))
+	assert.Equal(t, `^(?s:[^a-c]\.x)$`, fnmatchRegexp("[!a-c].x"))
+	assert.Equal(t, `^(?s:\[ab)$`, fnmatchRegexp("[ab"))
+	assert.True(t, fnmatch("web]", "web[]]"))
+}
+
+func TestPreviewTarget(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Do("/minions/", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"return": [{"minion1": {"os": "Ubuntu"}, "minion2": false, "minion3": {"os": "CentOS"}}]}`))
+	})
+
+	res, err := c.PreviewTarget(context.Background(), ExpressionTarget{Expression: "G@os:ubuntu or minion2", Type: Compound})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"minion1", "minion2"}, res)
+}
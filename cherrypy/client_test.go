@@ -3,6 +3,7 @@ package cherrypy
 import (
 	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
 	"testing"
 
@@ -30,7 +31,34 @@ func setup(t *testing.T) (*apiTester.Tester, *Client) {
 	}
 
 	client := NewClient(tester.URL, testUsername, testPassword, testEAuth, false)
-	client.Token = testToken
+	client.SetToken(testToken)
 
 	return tester, client
 }
+
+// setupSequence serves the scenarios of the category in order; the last one is repeated
+func setupSequence(t *testing.T, tester *apiTester.Tester, category string, scenarios ...string) *int {
+	responses := make([]*apiTester.TestScenario, len(scenarios))
+	for i, name := range scenarios {
+		s, err := tester.Scenario(category, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		responses[i] = s
+	}
+
+	calls := 0
+	tester.Do(responses[0].Request.Path, func(w http.ResponseWriter, req *http.Request) {
+		s := responses[len(responses)-1]
+		if calls < len(responses) {
+			s = responses[calls]
+		}
+
+		calls++
+		apiTester.CompareRequests(t, &s.Request, req)
+		apiTester.WriteResponse(t, &s.Response, w)
+	})
+
+	return &calls
+}
@@ -0,0 +1,329 @@
+package cherrypy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultWebSocketPingInterval = 30 * time.Second
+	webSocketWriteTimeout        = 10 * time.Second
+	webSocketReadyMessage        = "websocket client ready"
+)
+
+// errorTokenChanged indicates the connection was closed to use the token retrieved by a new Login()
+var errorTokenChanged = errors.New("token changed")
+
+// WebSocketOptions configures a WebSocket subscription to Salt's event bus
+type WebSocketOptions struct {
+	// FormatEvents requests Salt to process events before sending them.
+	// Events are delivered with "minions" or "jobs" tags containing the current state.
+	FormatEvents bool
+
+	// SaltToken is used instead of the token retrieved by Login() if set
+	SaltToken string
+
+	// PingInterval controls how often keepalive pings are sent; defaults to 30 seconds.
+	// Connection is re-established if no message or pong is received for two intervals.
+	PingInterval time.Duration
+}
+
+type wsStream struct {
+	client   *Client
+	opts     WebSocketOptions
+	token    string
+	loggedIn <-chan struct{}
+}
+
+/*
+WebSocketEvents subscribes to Salt's event bus over WebSocket
+
+Events are delivered on the returned channel until the context is cancelled.
+If the connection drops or Login() retrieves a new token, the connection is re-established.
+The channel is closed when the subscription ends.
+
+https://docs.saltstack.com/en/latest/ref/netapi/all/salt.netapi.rest_cherrypy.html#ws
+*/
+func (c *Client) WebSocketEvents(ctx context.Context, opts WebSocketOptions) (<-chan Event, error) {
+	if opts.SaltToken == "" && c.Token() == "" {
+		return nil, ErrorNotAuthenticated
+	}
+
+	if opts.PingInterval <= 0 {
+		opts.PingInterval = defaultWebSocketPingInterval
+	}
+
+	s := &wsStream{
+		client: c,
+		opts:   opts,
+	}
+
+	conn, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan Event)
+	go s.run(ctx, conn, ch)
+
+	return ch, nil
+}
+
+func (s *wsStream) dialer() *websocket.Dialer {
+	d := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 45 * time.Second,
+	}
+
+	if tr, ok := s.client.client.Transport.(*http.Transport); ok {
+		d.Proxy = tr.Proxy
+		d.TLSClientConfig = tr.TLSClientConfig
+	}
+
+	return d
+}
+
+func (s *wsStream) url() (string, error) {
+	u, err := url.Parse(s.client.Address)
+	if err != nil {
+		return "", err
+	}
+
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+
+	q := url.Values{}
+	if s.opts.SaltToken != "" {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/ws"
+		q.Set("salt_token", s.opts.SaltToken)
+	} else {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/ws/" + url.PathEscape(s.token)
+	}
+
+	u.RawQuery = q.Encode()
+	if s.opts.FormatEvents {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += "format_events"
+	}
+
+	return u.String(), nil
+}
+
+func (s *wsStream) connect(ctx context.Context) (*websocket.Conn, error) {
+	c := s.client
+	retried := false
+
+	for {
+		if s.opts.SaltToken == "" {
+			if c.shouldRenewToken() {
+				if err := c.renewToken(ctx); err != nil {
+					return nil, err
+				}
+			}
+
+			s.token = c.Token()
+			s.loggedIn = c.loginNotification()
+		}
+
+		u, err := s.url()
+		if err != nil {
+			return nil, err
+		}
+
+		header := http.Header{}
+		if c.userAgent != "" {
+			header.Set("User-Agent", c.userAgent)
+		}
+
+		c.debug("Sending WebSocket connection request", Field{"format_events", s.opts.FormatEvents})
+		conn, resp, err := s.dialer().DialContext(ctx, u, header)
+		if err != nil {
+			if resp == nil {
+				return nil, err
+			}
+
+			// Not checking for error as it does not matter
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			if resp.StatusCode == http.StatusUnauthorized && s.opts.SaltToken == "" && !retried {
+				if err := c.relogin(ctx, s.token, "ws"); err != nil {
+					return nil, err
+				}
+
+				retried = true
+				continue
+			}
+
+			return nil, newRequestError(resp, body)
+		}
+
+		// Salt does not send events until the client declares it is ready
+		conn.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(webSocketReadyMessage)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		return conn, nil
+	}
+}
+
+func (s *wsStream) run(ctx context.Context, conn *websocket.Conn, ch chan<- Event) {
+	defer close(ch)
+
+	for {
+		err := s.serve(ctx, conn, ch)
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.client.warn("WebSocket disconnected", Field{"error", err})
+		for {
+			if err != errorTokenChanged {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(defaultEventRetryDelay):
+				}
+			}
+
+			conn, err = s.connect(ctx)
+			if err == nil {
+				break
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			s.client.warn("Reconnecting WebSocket failed", Field{"error", err})
+		}
+	}
+}
+
+// serve reads events from the connection while keeping it alive until the connection is closed
+func (s *wsStream) serve(ctx context.Context, conn *websocket.Conn, ch chan<- Event) error {
+	done := make(chan struct{})
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- s.keepalive(ctx, conn, done)
+	}()
+
+	err := s.read(ctx, conn, ch)
+	close(done)
+	conn.Close()
+
+	if reason := <-stopped; reason != nil {
+		return reason
+	}
+
+	return err
+}
+
+func (s *wsStream) keepalive(ctx context.Context, conn *websocket.Conn, done <-chan struct{}) error {
+	loggedIn := s.loggedIn
+	t := time.NewTicker(s.opts.PingInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+			conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(webSocketWriteTimeout))
+			conn.Close()
+			return nil
+		case <-loggedIn:
+			if s.client.Token() != s.token {
+				conn.Close()
+				return errorTokenChanged
+			}
+
+			loggedIn = s.client.loginNotification()
+		case <-t.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(webSocketWriteTimeout)); err != nil {
+				conn.Close()
+				return err
+			}
+		}
+	}
+}
+
+func (s *wsStream) read(ctx context.Context, conn *websocket.Conn, ch chan<- Event) error {
+	timeout := 2 * s.opts.PingInterval
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(timeout))
+	})
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		conn.SetReadDeadline(time.Now().Add(timeout))
+
+		events, err := s.parse(msg)
+		if err != nil {
+			s.client.warn("Discarding malformed WebSocket message", Field{"error", err})
+			continue
+		}
+
+		for _, ev := range events {
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+func (s *wsStream) parse(msg []byte) ([]Event, error) {
+	data := strings.TrimSpace(string(msg))
+	if data == "" {
+		return nil, nil
+	}
+
+	if !s.opts.FormatEvents {
+		ev, err := parseEvent("", strings.TrimPrefix(data, "data: "))
+		if err != nil {
+			return nil, err
+		}
+
+		return []Event{*ev}, nil
+	}
+
+	// Formatted events are published as {"minions": {...}} or {"jobs": {...}}
+	var formatted map[string]map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &formatted); err != nil {
+		return nil, err
+	}
+
+	events := make([]Event, 0, len(formatted))
+	for k, v := range formatted {
+		events = append(events, Event{
+			Tag:  k,
+			Data: v,
+		})
+	}
+
+	return events, nil
+}
@@ -0,0 +1,156 @@
+package cherrypy
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+var testUpgrader = websocket.Upgrader{}
+
+func serveWebSocket(t *testing.T, messages ...string) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		conn, err := testUpgrader.Upgrade(w, req, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		_, ready, err := conn.ReadMessage()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		assert.Equal(t, webSocketReadyMessage, string(ready))
+
+		for _, m := range messages {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(m)); err != nil {
+				return
+			}
+		}
+
+		// Keep the connection open until the client goes away
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func TestWebSocketEvents(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Do("/ws/"+testToken, serveWebSocket(t, "data: "+testEventJobNew+"\n\n"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := c.WebSocketEvents(ctx, WebSocketOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ev := receiveEvent(t, ch)
+	assert.Equal(t, "salt/job/20200202224725441938/new", ev.Tag)
+	assert.Equal(t, "20200202224725441938", ev.Data["jid"])
+	assert.Equal(t, time.Date(2020, time.February, 2, 22, 47, 25, 490631000, time.UTC), ev.Timestamp)
+
+	cancel()
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func TestWebSocketFormatEvents(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Do("/ws/"+testToken, func(w http.ResponseWriter, req *http.Request) {
+		_, ok := req.URL.Query()["format_events"]
+		assert.True(t, ok)
+		serveWebSocket(t, `{"minions": {"minion1": {"id": "minion1"}}}`+"\n\n")(w, req)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := c.WebSocketEvents(ctx, WebSocketOptions{FormatEvents: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ev := receiveEvent(t, ch)
+	assert.Equal(t, "minions", ev.Tag)
+	assert.Contains(t, ev.Data, "minion1")
+}
+
+func TestWebSocketPing(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+
+	pinged := make(chan struct{}, 1)
+	tester.Do("/ws/"+testToken, func(w http.ResponseWriter, req *http.Request) {
+		conn, err := testUpgrader.Upgrade(w, req, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		conn.SetPingHandler(func(data string) error {
+			select {
+			case pinged <- struct{}{}:
+			default:
+			}
+			return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		})
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := c.WebSocketEvents(ctx, WebSocketOptions{PingInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-pinged:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for ping")
+	}
+}
+
+func TestWebSocketReconnectAfterLogin(t *testing.T) {
+	tester, c := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "auth_login", "success")
+	tester.Do("/ws/expired", serveWebSocket(t))
+	tester.Do("/ws/"+testToken, serveWebSocket(t, "data: "+testEventJobNew+"\n\n"))
+
+	c.SetToken("expired")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := c.WebSocketEvents(ctx, WebSocketOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Login(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ev := receiveEvent(t, ch)
+	assert.Equal(t, "salt/job/20200202224725441938/new", ev.Tag)
+}
@@ -29,6 +29,11 @@ type saltTime struct {
 func (t *saltTime) UnmarshalJSON(input []byte) error {
 	s := string(input)
 	s = strings.Trim(s, "\"")
+	if s == "" {
+		// Sent for jobs which were not found
+		return nil
+	}
+
 	v, err := time.Parse("2006, Jan 02 15:04:05.000000", s)
 	if err != nil {
 		return err
@@ -0,0 +1,164 @@
+package cherrypy
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// LogLevel indicates severity of a log message
+type LogLevel int
+
+const (
+	// LogLevelDebug is used for request and response details
+	LogLevelDebug LogLevel = iota
+	// LogLevelInfo is used for session changes such as logging in
+	LogLevelInfo
+	// LogLevelWarn is used for failures the client recovers from
+	LogLevelWarn
+	// LogLevelError is used for failures the client cannot recover from
+	LogLevelError
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveFields contain keys of fields which are never logged
+var sensitiveFields = map[string]bool{
+	"token":        true,
+	"salt_token":   true,
+	"x-auth-token": true,
+	"password":     true,
+	"ssh_passwd":   true,
+}
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "DEBUG"
+	case LogLevelInfo:
+		return "INFO"
+	case LogLevelWarn:
+		return "WARN"
+	case LogLevelError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("LEVEL(%d)", int(l))
+	}
+}
+
+// Field is a key-value pair attached to a log message
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+/*
+Logger receives log messages from Client
+
+Fields named like credentials are redacted before they are passed to the logger; the token and the password
+are replaced wherever they appear in other values (e.g.: in the text of an error).
+Client does not log anything unless a logger is set with WithLogger().
+*/
+type Logger interface {
+	Log(level LogLevel, msg string, fields ...Field)
+}
+
+type stdLogger struct {
+	logger *log.Logger
+	level  LogLevel
+}
+
+/*
+NewStdLogger returns a Logger writing messages at or above the level to a standard library logger
+
+Messages are formatted as:
+	[DEBUG] Received response method=GET endpoint=/minions status=200 duration=12ms
+*/
+func NewStdLogger(logger *log.Logger, level LogLevel) Logger {
+	return &stdLogger{
+		logger: logger,
+		level:  level,
+	}
+}
+
+func (l *stdLogger) Log(level LogLevel, msg string, fields ...Field) {
+	if level < l.level {
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s", level, msg)
+	for _, f := range fields {
+		fmt.Fprintf(&b, " %s=%v", f.Key, f.Value)
+	}
+
+	l.logger.Print(b.String())
+}
+
+// WithLogger sets the logger receiving messages from the client
+func WithLogger(logger Logger) Option {
+	return func(o *clientOptions) error {
+		o.logger = logger
+		return nil
+	}
+}
+
+func (c *Client) debug(msg string, fields ...Field) {
+	c.log(LogLevelDebug, msg, fields...)
+}
+
+func (c *Client) warn(msg string, fields ...Field) {
+	c.log(LogLevelWarn, msg, fields...)
+}
+
+func (c *Client) log(level LogLevel, msg string, fields ...Field) {
+	if c.logger == nil {
+		return
+	}
+
+	secrets := c.secrets()
+	for i, f := range fields {
+		if sensitiveFields[strings.ToLower(f.Key)] {
+			fields[i].Value = redacted
+		} else if v, ok := redactSecrets(f.Value, secrets); ok {
+			fields[i].Value = v
+		}
+	}
+
+	c.logger.Log(level, msg, fields...)
+}
+
+// secrets returns the credentials held by the client which must not be logged
+func (c *Client) secrets() []string {
+	var secrets []string
+	if token := c.Token(); token != "" {
+		secrets = append(secrets, token)
+	}
+
+	if c.eauth != nil && c.eauth.Password != "" {
+		secrets = append(secrets, c.eauth.Password)
+	}
+
+	return secrets
+}
+
+/*
+redactSecrets formats the value and replaces the secrets it contains (e.g.: a token in the URL of an error);
+the value is only replaced if it contains one of them
+*/
+func redactSecrets(v interface{}, secrets []string) (string, bool) {
+	if v == nil || len(secrets) == 0 {
+		return "", false
+	}
+
+	s := fmt.Sprint(v)
+	found := false
+	for _, secret := range secrets {
+		if strings.Contains(s, secret) {
+			s = strings.Replace(s, secret, redacted, -1)
+			found = true
+		}
+	}
+
+	return s, found
+}
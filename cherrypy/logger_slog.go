@@ -0,0 +1,34 @@
+//go:build go1.21
+// +build go1.21
+
+package cherrypy
+
+import (
+	"context"
+	"log/slog"
+)
+
+var slogLevels = map[LogLevel]slog.Level{
+	LogLevelDebug: slog.LevelDebug,
+	LogLevelInfo:  slog.LevelInfo,
+	LogLevelWarn:  slog.LevelWarn,
+	LogLevelError: slog.LevelError,
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger passing messages and fields to a log/slog logger
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return &slogLogger{logger: logger}
+}
+
+func (l *slogLogger) Log(level LogLevel, msg string, fields ...Field) {
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.Any(f.Key, f.Value)
+	}
+
+	l.logger.LogAttrs(context.Background(), slogLevels[level], msg, attrs...)
+}
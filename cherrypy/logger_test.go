@@ -0,0 +1,102 @@
+package cherrypy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testLogEntry struct {
+	level  LogLevel
+	msg    string
+	fields []Field
+}
+
+type testLogger struct {
+	entries []testLogEntry
+}
+
+func (l *testLogger) Log(level LogLevel, msg string, fields ...Field) {
+	l.entries = append(l.entries, testLogEntry{level, msg, fields})
+}
+
+func (l *testLogger) String() string {
+	var b strings.Builder
+	for _, e := range l.entries {
+		fmt.Fprintf(&b, "%s %s %v\n", e.level, e.msg, e.fields)
+	}
+
+	return b.String()
+}
+
+func TestLoggerRedactsCredentials(t *testing.T) {
+	tester, _ := setup(t)
+	defer tester.Close()
+	tester.Setup(t, "auth_login", "success")
+	tester.Setup(t, "auth_logout", "success")
+
+	logger := &testLogger{}
+	c, err := NewClientWithOptions(tester.URL,
+		WithCredentials(testUsername, testPassword, testEAuth),
+		WithLogger(logger),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Login(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.debug("Sensitive", Field{"token", "value"}, Field{"header", testToken}, Field{"Password", "value"})
+	err = c.Logout(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := logger.String()
+	assert.NotContains(t, out, testToken)
+	assert.NotContains(t, out, testPassword)
+	assert.Contains(t, out, "[{token [REDACTED]} {header [REDACTED]} {Password [REDACTED]}]")
+	assert.Contains(t, out, "DEBUG Received response [{method POST} {endpoint /login} {status 200}")
+}
+
+func TestLoggerRedactsSecretsInValues(t *testing.T) {
+	logger := &testLogger{}
+	c, err := NewClientWithOptions("http://master:8000",
+		WithCredentials(testUsername, testPassword, testEAuth),
+		WithLogger(logger),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetToken(testToken)
+
+	reqErr := &url.Error{Op: "Get", URL: "http://master:8000/events?token=" + testToken, Err: errors.New("connection refused")}
+	c.warn("Request failed", Field{"error", reqErr}, Field{"body", "password=" + testPassword}, Field{"status", 503})
+
+	out := logger.String()
+	assert.NotContains(t, out, testToken)
+	assert.NotContains(t, out, testPassword)
+	assert.Contains(t, out, `{error Get "http://master:8000/events?token=[REDACTED]": connection refused}`)
+	assert.Contains(t, out, "{body password=[REDACTED]} {status 503}")
+	assert.Equal(t, 503, logger.entries[0].fields[2].Value)
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewStdLogger(log.New(&buf, "", 0), LogLevelInfo)
+
+	logger.Log(LogLevelDebug, "Hidden")
+	logger.Log(LogLevelWarn, "Request failed", Field{"endpoint", "/run"}, Field{"status", 503})
+
+	assert.Equal(t, "[WARN] Request failed endpoint=/run status=503\n", buf.String())
+}
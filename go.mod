@@ -4,5 +4,6 @@ go 1.13
 
 require (
 	github.com/finarfin/go-apiclient-tester v0.0.1
+	github.com/gorilla/websocket v1.4.2
 	github.com/stretchr/testify v1.4.0
 )